@@ -0,0 +1,90 @@
+package oax
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// resolveAPIKey returns the API key of the profile from apiKey, apiKeyEnv,
+// apiKeyCommand or the key store, and where it came from.
+func (p Profile) resolveAPIKey(keyStore *KeyStore) (string, string, error) {
+	switch {
+	case p.ApiKey != "":
+		return p.ApiKey, fmt.Sprintf("profile %s apiKey (%s)", p.Name, profileFilePath), nil
+	case p.ApiKeyEnv != "":
+		apiKey := os.Getenv(p.ApiKeyEnv)
+		if apiKey == "" {
+			return "", "", fmt.Errorf("environment variable %s of profile %s is not set", p.ApiKeyEnv, p.Name)
+		}
+
+		return apiKey, fmt.Sprintf("profile %s apiKeyEnv (%s)", p.Name, envSource(p.ApiKeyEnv)), nil
+	case p.ApiKeyCommand != "":
+		apiKey, err := runAPIKeyCommand(p.ApiKeyCommand)
+		if err != nil {
+			return "", "", fmt.Errorf("apiKeyCommand of profile %s: %w", p.Name, err)
+		}
+
+		return apiKey, fmt.Sprintf("profile %s apiKeyCommand (%s)", p.Name, p.ApiKeyCommand), nil
+	case p.ApiKeyStore:
+		apiKey, err := keyStore.Get(p.Name)
+		if err != nil {
+			return "", "", err
+		}
+
+		return apiKey, fmt.Sprintf("profile %s apiKeyStore (%s)", p.Name, keyStore.FilePath), nil
+	default:
+		return "", "", fmt.Errorf("profile %s has no apiKey, apiKeyEnv, apiKeyCommand or apiKeyStore", p.Name)
+	}
+}
+
+// apiKeyField names the field of the profile that resolveAPIKey uses.
+func (p Profile) apiKeyField() string {
+	switch {
+	case p.ApiKey != "":
+		return "apiKey"
+	case p.ApiKeyEnv != "":
+		return "apiKeyEnv " + p.ApiKeyEnv
+	case p.ApiKeyCommand != "":
+		return "apiKeyCommand"
+	case p.ApiKeyStore:
+		return "apiKeyStore"
+	default:
+		return "without an API key"
+	}
+}
+
+// runAPIKeyCommand runs command with the shell and returns the first line
+// of its output, like password managers such as pass print it.
+func runAPIKeyCommand(command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("%w: %s", err, message)
+		}
+
+		return "", err
+	}
+
+	apiKey, _, _ := strings.Cut(string(out), "\n")
+	apiKey = strings.TrimSpace(apiKey)
+	if apiKey == "" {
+		return "", fmt.Errorf("%q printed no API key", command)
+	}
+
+	return apiKey, nil
+}
@@ -1,6 +1,7 @@
 package oax
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -10,23 +11,63 @@ import (
 	"unicode"
 
 	"github.com/itchyny/timefmt-go"
-	"github.com/pelletier/go-toml"
 	"github.com/shuntaka9576/oax/openai"
 )
 
 type ChatMessage struct {
-	Role    string `toml:"role"`
-	Content string `toml:"content"`
+	Role    string `toml:"role" json:"role"`
+	Content string `toml:"content" json:"content"`
 }
 
+// ChatLogToml is the content of a chat log: the header fields followed by
+// the messages.
 type ChatLogToml struct {
-	Messages []ChatMessage `toml:"messages"`
+	Model string   `toml:"model" json:"model,omitempty"`
+	Tags  []string `toml:"tags" json:"tags,omitempty"`
+	// Templates records the templates applied to the conversation as
+	// "name:mode". See ApplyTemplate.
+	Templates []string `toml:"templates" json:"templates,omitempty"`
+	// Source identifies the conversation an imported chat log was created
+	// from, as "<format>:<id>", so that importing it again skips it.
+	Source   string        `toml:"source" json:"source,omitempty"`
+	Messages []ChatMessage `toml:"messages" json:"-"`
+}
+
+type chatLogHeaderField struct {
+	Key   string
+	Value interface{}
+}
+
+// headerFields lists the header fields that are set, in the order they are
+// written to a chat log.
+func (c ChatLogToml) headerFields() []chatLogHeaderField {
+	var fields []chatLogHeaderField
+
+	if c.Model != "" {
+		fields = append(fields, chatLogHeaderField{"model", c.Model})
+	}
+	if len(c.Tags) > 0 {
+		fields = append(fields, chatLogHeaderField{"tags", c.Tags})
+	}
+	if len(c.Templates) > 0 {
+		fields = append(fields, chatLogHeaderField{"templates", c.Templates})
+	}
+	if c.Source != "" {
+		fields = append(fields, chatLogHeaderField{"source", c.Source})
+	}
+
+	return fields
 }
 
 type ChatLog struct {
 	ConfigDir   string
 	ChatLogToml ChatLogToml
 	FilePath    *string
+	Format      ChatLogFormat
+	// OnFlush, when set, is called after the chat log has been written by
+	// FlushFile.
+	OnFlush func(c *ChatLog) error
+	lock    *fileLock
 }
 
 func (c *ChatLog) AddChatMessage(chatMessage ChatMessage) *ChatLog {
@@ -51,25 +92,68 @@ func (c *ChatLog) DeleteFile() error {
 		return err
 	}
 
+	for _, suffix := range []string{backupFileSuffix, headerFileSuffix} {
+		err = os.Remove(*c.FilePath + suffix)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// Lock takes an advisory lock on the chat log so that another oax process
+// resuming the same file fails instead of overwriting it.
+func (c *ChatLog) Lock() error {
+	if c.lock != nil {
+		return nil
+	}
+
+	lock, err := lockFile(*c.FilePath)
+	if err != nil {
+		return err
+	}
+	c.lock = lock
+
+	return nil
+}
+
+func (c *ChatLog) Unlock() error {
+	if c.lock == nil {
+		return nil
+	}
+
+	err := c.lock.unlock()
+	c.lock = nil
+
+	return err
+}
+
 func (c *ChatLog) LoadLogMessage() error {
 	data, err := ioutil.ReadFile(*c.FilePath)
 	if err != nil {
 		return err
 	}
 
-	var chatLogToml ChatLogToml
-	err = toml.Unmarshal(data, &chatLogToml)
+	chatLogToml, err := c.format().Unmarshal(data)
 	if err != nil {
 		return err
 	}
 
-	c.ChatLogToml.Messages = chatLogToml.Messages
+	if _, ok := c.format().(separateHeaderFormat); ok {
+		data, err := os.ReadFile(*c.FilePath + headerFileSuffix)
+		if err == nil {
+			err = json.Unmarshal(data, &chatLogToml)
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("%s: %w", *c.FilePath+headerFileSuffix, err)
+		}
+	}
+
+	c.ChatLogToml = chatLogToml
 
 	for i := range c.ChatLogToml.Messages {
-		c.ChatLogToml.Messages[i].Content = strings.TrimRightFunc(c.ChatLogToml.Messages[i].Content, unicode.IsSpace)
+		c.ChatLogToml.Messages[i].Content = trimTrailingSpace(c.ChatLogToml.Messages[i].Content)
 	}
 
 	return nil
@@ -87,29 +171,65 @@ func (c *ChatLog) CreateOpenAIMessages() (messages []openai.Message) {
 }
 
 func (c *ChatLog) FlushFile() error {
-	var builder strings.Builder
+	data, err := c.format().Marshal(c.ChatLogToml)
+	if err != nil {
+		return err
+	}
 
-	for _, message := range c.ChatLogToml.Messages {
-		builder.WriteString(fmt.Sprintf(`[[messages]]
-  role = "%s"
-  content = '''
-%s
-'''
+	err = writeFileAtomic(*c.FilePath, data, 0644, true)
+	if err != nil {
+		return err
+	}
+
+	if err := c.flushHeaderFile(); err != nil {
+		return err
+	}
+
+	if c.OnFlush != nil {
+		return c.OnFlush(c)
+	}
+
+	return nil
+}
 
-`, message.Role, message.Content))
+// flushHeaderFile writes the header fields of a format that keeps them out
+// of the chat log, and removes the header file when none are set.
+func (c *ChatLog) flushHeaderFile() error {
+	if _, ok := c.format().(separateHeaderFormat); !ok {
+		return nil
 	}
 
-	err := ioutil.WriteFile(*c.FilePath, []byte(builder.String()), 0644)
+	headerFilePath := *c.FilePath + headerFileSuffix
+	if len(c.ChatLogToml.headerFields()) == 0 {
+		err := os.Remove(headerFilePath)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		return nil
+	}
+
+	data, err := json.Marshal(c.ChatLogToml)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return writeFileAtomic(headerFilePath, append(data, '\n'), 0644, false)
 }
 
 func (c *ChatLog) InitLogFile(title string, fileNameFormat string) {
+	c.InitLogFileAt(title, fileNameFormat, time.Now())
+}
+
+// InitLogFileAt names the chat log after the given time instead of now,
+// which is used when importing conversations with their original
+// timestamps. An existing file is never reused; a numeric suffix is added
+// instead.
+func (c *ChatLog) InitLogFileAt(title string, fileNameFormat string, t time.Time) {
 	var useFormat string
 
+	title = sanitizeFileName(title)
+
 	if fileNameFormat == "" {
 		if title == "" {
 			useFormat = "%Y-%m-%d_%H-%M-%S"
@@ -120,12 +240,15 @@ func (c *ChatLog) InitLogFile(title string, fileNameFormat string) {
 		useFormat = fileNameFormat
 	}
 
-	useFormat = strings.ReplaceAll(useFormat, "${title}", title)
+	useFormat = strings.ReplaceAll(useFormat, "${title}", strings.ReplaceAll(title, "%", "%%"))
 
-	t := time.Now()
-	filename := timefmt.Format(t, useFormat) + ".toml"
+	name := timefmt.Format(t, useFormat)
+	ext := c.format().Extension()
 
-	filePath := filepath.Join(c.ConfigDir, filename)
+	filePath := filepath.Join(c.ConfigDir, name+ext)
+	for i := 2; fileExists(filePath); i++ {
+		filePath = filepath.Join(c.ConfigDir, fmt.Sprintf("%s_%d%s", name, i, ext))
+	}
 	c.FilePath = &filePath
 }
 
@@ -136,6 +259,19 @@ func (c *ChatLog) LoadFile(filePath string) error {
 	}
 
 	c.FilePath = &filePath
+	c.Format = ChatLogFormatByPath(filePath)
 
 	return nil
 }
+
+func (c *ChatLog) format() ChatLogFormat {
+	if c.Format == nil {
+		return TomlChatLogFormat{}
+	}
+
+	return c.Format
+}
+
+func trimTrailingSpace(s string) string {
+	return strings.TrimRightFunc(s, unicode.IsSpace)
+}
@@ -0,0 +1,84 @@
+package oax
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ChatLogFormat converts chat messages to and from the on-disk
+// representation of a chat log.
+type ChatLogFormat interface {
+	Name() string
+	Extension() string
+	Marshal(chatLogToml ChatLogToml) ([]byte, error)
+	Unmarshal(data []byte) (ChatLogToml, error)
+}
+
+// separateHeaderFormat is implemented by formats that cannot hold the
+// header fields, which are then kept in the file at the path of the chat
+// log with headerFileSuffix.
+type separateHeaderFormat interface {
+	separateHeader()
+}
+
+const DefaultChatLogFormat = "toml"
+
+var chatLogFormats = []ChatLogFormat{
+	TomlChatLogFormat{},
+	MarkdownChatLogFormat{},
+	JSONLChatLogFormat{},
+}
+
+// ChatLogFormatByName returns the format for the chatLogFormat setting.
+// An empty name selects TOML.
+func ChatLogFormatByName(name string) (ChatLogFormat, error) {
+	if name == "" {
+		name = DefaultChatLogFormat
+	}
+
+	for _, format := range chatLogFormats {
+		if strings.EqualFold(format.Name(), name) {
+			return format, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown chat log format %q (supported: %s)", name, strings.Join(ChatLogFormatNames(), ", "))
+}
+
+// ChatLogFormatByPath detects the format from the file extension, falling
+// back to TOML for unknown extensions.
+func ChatLogFormatByPath(filePath string) ChatLogFormat {
+	if format, ok := chatLogFormatByExtension(filepath.Ext(filePath)); ok {
+		return format
+	}
+
+	return TomlChatLogFormat{}
+}
+
+// IsChatLogFile reports whether the file has the extension of one of the
+// supported chat log formats.
+func IsChatLogFile(filePath string) bool {
+	_, ok := chatLogFormatByExtension(filepath.Ext(filePath))
+
+	return ok
+}
+
+func chatLogFormatByExtension(ext string) (ChatLogFormat, bool) {
+	for _, format := range chatLogFormats {
+		if strings.EqualFold(format.Extension(), ext) {
+			return format, true
+		}
+	}
+
+	return nil, false
+}
+
+func ChatLogFormatNames() []string {
+	var names []string
+	for _, format := range chatLogFormats {
+		names = append(names, format.Name())
+	}
+
+	return names
+}
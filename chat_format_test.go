@@ -0,0 +1,156 @@
+package oax
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChatLogFormatRoundTrip(t *testing.T) {
+	chatLogToml := ChatLogToml{Model: "gpt-4", Source: "chatgpt:c-1"}
+	chatLogToml.Messages = []ChatMessage{
+		{Role: "system", Content: "You are a helpful assistant."},
+		{Role: "user", Content: "What does this print?\n\n```go\nfmt.Println(`## user`)\n```"},
+		{Role: "assistant", Content: "## Answer\n\n## user\n\\## assistant\n\"quoted\" <b>"},
+		{Role: "function", Content: "{\"result\": 1}\n## tool_call"},
+		{Role: "assistant", Content: "Done."},
+	}
+
+	for _, format := range chatLogFormats {
+		t.Run(format.Name(), func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "chat"+format.Extension())
+			chatLog := &ChatLog{FilePath: &filePath, Format: format, ChatLogToml: chatLogToml}
+			if err := chatLog.FlushFile(); err != nil {
+				t.Fatalf("Error: Return err func: %v", err)
+			}
+
+			got := &ChatLog{FilePath: &filePath, Format: format}
+			if err := got.LoadLogMessage(); err != nil {
+				t.Fatalf("Error: Return err func: %v", err)
+			}
+
+			if !reflect.DeepEqual(got.ChatLogToml, chatLogToml) {
+				t.Errorf("Expected %q but got %q", chatLogToml, got.ChatLogToml)
+			}
+		})
+	}
+}
+
+func TestJSONLChatLogHeaderFile(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "chat.jsonl")
+	chatLog := &ChatLog{FilePath: &filePath, Format: JSONLChatLogFormat{}}
+	chatLog.ChatLogToml = ChatLogToml{
+		Model:    "gpt-4",
+		Tags:     []string{"go"},
+		Messages: []ChatMessage{{Role: "user", Content: "hi"}, {Role: "assistant", Content: "hello"}},
+	}
+	if err := chatLog.FlushFile(); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+
+	// A reader that only knows messages reads every line as one.
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	for _, line := range lines {
+		var message ChatMessage
+		decoder := json.NewDecoder(strings.NewReader(line))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&message); err != nil || message.Role == "" {
+			t.Errorf("Expected a message but got %s: %v", line, err)
+		}
+	}
+	if len(lines) != 2 {
+		t.Errorf("Expected 2 lines but got %q", lines)
+	}
+
+	newFilePath := filepath.Join(dir, "renamed.jsonl")
+	if err := MoveChatLog(filePath, newFilePath); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	moved, err := LoadChatLog(newFilePath)
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if moved.ChatLogToml.Model != "gpt-4" || !reflect.DeepEqual(moved.ChatLogToml.Tags, []string{"go"}) {
+		t.Errorf("Expected the header to move with the chat log but got %+v", moved.ChatLogToml)
+	}
+
+	if err := moved.DeleteFile(); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if _, err := os.Stat(newFilePath + headerFileSuffix); !os.IsNotExist(err) {
+		t.Errorf("Expected the header file to be deleted but got %v", err)
+	}
+}
+
+func TestJSONLChatLogFormatInlineHeader(t *testing.T) {
+	input := `{"model":"gpt-4","tags":["go"]}
+{"role":"user","content":"hi"}
+`
+
+	got, err := (JSONLChatLogFormat{}).Unmarshal([]byte(input))
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if got.Model != "gpt-4" || len(got.Messages) != 1 {
+		t.Errorf("Unexpected chat log %+v", got)
+	}
+}
+
+func TestMarkdownChatLogFormatInvalidRole(t *testing.T) {
+	chatLogToml := ChatLogToml{Messages: []ChatMessage{{Role: "Tool Call", Content: "x"}}}
+
+	if _, err := (MarkdownChatLogFormat{}).Marshal(chatLogToml); err == nil {
+		t.Errorf("Expected an error for role %q", "Tool Call")
+	}
+}
+
+func TestMarkdownChatLogFormatFrontMatter(t *testing.T) {
+	input := `---
+model: gpt-4
+---
+
+## user
+
+hi
+`
+
+	got, err := MarkdownChatLogFormat{}.Unmarshal([]byte(input))
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if got.Model != "gpt-4" {
+		t.Errorf("Expected %q but got %q", "gpt-4", got.Model)
+	}
+	if len(got.Messages) != 1 {
+		t.Errorf("Expected 1 message but got %d", len(got.Messages))
+	}
+}
+
+func TestChatLogFormatByPath(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"/log/2023-03-26_17-01-54.toml", "toml"},
+		{"/log/2023-03-26_17-01-54.md", "markdown"},
+		{"/log/2023-03-26_17-01-54.JSONL", "jsonl"},
+		{"/log/2023-03-26_17-01-54", "toml"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			result := ChatLogFormatByPath(tc.input).Name()
+			if result != tc.expected {
+				t.Errorf("Expected %q but got %q", tc.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package oax
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// JSONLChatLogFormat stores one {"role": ..., "content": ...} object per
+// line, which is convenient for tooling. Header fields are kept in a
+// separate file so that every line is a message. A first line without a
+// "role", which older versions wrote for the header, is still read.
+type JSONLChatLogFormat struct{}
+
+func (JSONLChatLogFormat) separateHeader() {}
+
+func (JSONLChatLogFormat) Name() string      { return "jsonl" }
+func (JSONLChatLogFormat) Extension() string { return ".jsonl" }
+
+func (JSONLChatLogFormat) Marshal(chatLogToml ChatLogToml) ([]byte, error) {
+	var buf bytes.Buffer
+
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+
+	for _, message := range chatLogToml.Messages {
+		if err := encoder.Encode(message); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (JSONLChatLogFormat) Unmarshal(data []byte) (ChatLogToml, error) {
+	var chatLogToml ChatLogToml
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), len(data)+1)
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var message struct {
+			Role    *string `json:"role"`
+			Content string  `json:"content"`
+		}
+		if err := json.Unmarshal(line, &message); err != nil {
+			return ChatLogToml{}, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		if message.Role == nil {
+			if err := json.Unmarshal(line, &chatLogToml); err != nil {
+				return ChatLogToml{}, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+
+			continue
+		}
+
+		chatLogToml.Messages = append(chatLogToml.Messages, ChatMessage{
+			Role:    *message.Role,
+			Content: message.Content,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return ChatLogToml{}, err
+	}
+
+	return chatLogToml, nil
+}
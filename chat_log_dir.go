@@ -0,0 +1,285 @@
+package oax
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ArchiveDirName is the subdirectory of the chat log directory that
+	// archived chat logs are moved to. The resume picker skips it unless
+	// asked to include archived logs.
+	ArchiveDirName = "archive"
+	// TrashDirName holds chat logs removed with `oax log rm` until they
+	// are restored.
+	TrashDirName = ".trash"
+
+	trashOriginSuffix = ".origin"
+)
+
+// IsArchived reports whether filePath is inside the archive of chatLogDir.
+func IsArchived(chatLogDir string, filePath string) bool {
+	return topLevelDir(chatLogDir, filePath) == ArchiveDirName
+}
+
+func topLevelDir(dir string, filePath string) string {
+	rel, err := filepath.Rel(dir, filePath)
+	if err != nil {
+		return ""
+	}
+
+	parts := strings.SplitN(filepath.ToSlash(rel), "/", 2)
+	if len(parts) < 2 {
+		return ""
+	}
+
+	return parts[0]
+}
+
+// ResolveChatLogPath finds a chat log given as a path, or as a file name
+// (with or without extension) relative to chatLogDir or its archive.
+func ResolveChatLogPath(chatLogDir string, name string) (string, error) {
+	name, err := replaceTildeWithHomedir(name)
+	if err != nil {
+		return "", err
+	}
+
+	candidates := []string{name}
+	if !filepath.IsAbs(name) {
+		candidates = append(candidates,
+			filepath.Join(chatLogDir, name),
+			filepath.Join(chatLogDir, ArchiveDirName, name),
+		)
+	}
+
+	for _, candidate := range candidates {
+		if fileExists(candidate) {
+			return candidate, nil
+		}
+		for _, format := range chatLogFormats {
+			if fileExists(candidate + format.Extension()) {
+				return candidate + format.Extension(), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("chat log not found: %s", name)
+}
+
+// MoveChatLog renames a chat log together with its backup and header file. The chat log is
+// locked during the move so that a running chat session is not pulled out
+// from under it. The format is detected from the extension, so the
+// extension cannot change.
+func MoveChatLog(filePath string, newFilePath string) error {
+	if fileExists(newFilePath) {
+		return fmt.Errorf("%s already exists", newFilePath)
+	}
+	if ext, newExt := ChatLogFormatByPath(filePath).Extension(), ChatLogFormatByPath(newFilePath).Extension(); ext != newExt {
+		return fmt.Errorf("cannot rename a %s chat log to %s: renaming does not convert the format", ext, newExt)
+	}
+
+	chatLog := ChatLog{FilePath: &filePath}
+	if err := chatLog.Lock(); err != nil {
+		return err
+	}
+	defer chatLog.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(newFilePath), 0755); err != nil {
+		return err
+	}
+
+	if err := os.Rename(filePath, newFilePath); err != nil {
+		return err
+	}
+
+	for _, suffix := range []string{backupFileSuffix, headerFileSuffix} {
+		err := os.Rename(filePath+suffix, newFilePath+suffix)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ArchiveChatLog moves a chat log into the archive and returns its new path.
+func ArchiveChatLog(chatLogDir string, filePath string) (string, error) {
+	if IsArchived(chatLogDir, filePath) {
+		return "", fmt.Errorf("%s is already archived", filePath)
+	}
+
+	newFilePath := filepath.Join(chatLogDir, ArchiveDirName, filepath.Base(filePath))
+	if err := MoveChatLog(filePath, newFilePath); err != nil {
+		return "", err
+	}
+
+	return newFilePath, nil
+}
+
+// UnarchiveChatLog moves an archived chat log back to the chat log
+// directory and returns its new path.
+func UnarchiveChatLog(chatLogDir string, filePath string) (string, error) {
+	if !IsArchived(chatLogDir, filePath) {
+		return "", fmt.Errorf("%s is not archived", filePath)
+	}
+
+	newFilePath := filepath.Join(chatLogDir, filepath.Base(filePath))
+	if err := MoveChatLog(filePath, newFilePath); err != nil {
+		return "", err
+	}
+
+	return newFilePath, nil
+}
+
+// TrashChatLog moves a chat log into the trash, remembering where it came
+// from so that RestoreTrashedChatLog can put it back.
+func TrashChatLog(chatLogDir string, filePath string) (string, error) {
+	trashDir := filepath.Join(chatLogDir, TrashDirName)
+	if err := os.MkdirAll(trashDir, 0755); err != nil {
+		return "", err
+	}
+
+	trashPath := filepath.Join(trashDir, fmt.Sprintf("%d_%s", time.Now().UnixNano(), filepath.Base(filePath)))
+
+	if err := os.WriteFile(trashPath+trashOriginSuffix, []byte(filePath), 0644); err != nil {
+		return "", err
+	}
+
+	if err := MoveChatLog(filePath, trashPath); err != nil {
+		os.Remove(trashPath + trashOriginSuffix)
+
+		return "", err
+	}
+
+	return trashPath, nil
+}
+
+// RestoreTrashedChatLog moves the most recently trashed chat log back to
+// where it was and returns that path.
+func RestoreTrashedChatLog(chatLogDir string) (string, error) {
+	trashDir := filepath.Join(chatLogDir, TrashDirName)
+
+	entries, err := os.ReadDir(trashDir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	type trashed struct {
+		path      string
+		trashedAt int64
+	}
+	var trashedLogs []trashed
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, trashOriginSuffix) {
+			continue
+		}
+
+		prefix, _, _ := strings.Cut(name, "_")
+		trashedAt, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			continue
+		}
+		trashedLogs = append(trashedLogs, trashed{
+			path:      filepath.Join(trashDir, strings.TrimSuffix(name, trashOriginSuffix)),
+			trashedAt: trashedAt,
+		})
+	}
+
+	if len(trashedLogs) == 0 {
+		return "", fmt.Errorf("the trash is empty")
+	}
+
+	sort.Slice(trashedLogs, func(i, j int) bool {
+		return trashedLogs[i].trashedAt > trashedLogs[j].trashedAt
+	})
+	last := trashedLogs[0]
+
+	origin, err := os.ReadFile(last.path + trashOriginSuffix)
+	if err != nil {
+		return "", err
+	}
+
+	if err := MoveChatLog(last.path, string(origin)); err != nil {
+		return "", err
+	}
+
+	if err := os.Remove(last.path + trashOriginSuffix); err != nil {
+		return "", err
+	}
+
+	return string(origin), nil
+}
+
+// SetTags adds and removes tags in the header of the chat log at filePath.
+func SetTags(filePath string, add []string, remove []string) ([]string, error) {
+	chatLog := &ChatLog{
+		ConfigDir: filepath.Dir(filePath),
+	}
+	if err := chatLog.LoadFile(filePath); err != nil {
+		return nil, err
+	}
+
+	// The chat log is read under the lock so that a change written by a
+	// chat session in between is not overwritten.
+	if err := chatLog.Lock(); err != nil {
+		return nil, err
+	}
+	defer chatLog.Unlock()
+
+	if err := chatLog.LoadLogMessage(); err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+
+	var tags []string
+	for _, tag := range append(chatLog.ChatLogToml.Tags, add...) {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || containsString(tags, tag) || containsString(remove, tag) {
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	chatLog.ChatLogToml.Tags = tags
+
+	if err := chatLog.FlushFile(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// BranchChatLog copies the conversation of chatLog to a new chat log next
+// to it, named after it with a "_branch" suffix, so that the conversation
+// can continue in another direction while the original is kept.
+func BranchChatLog(chatLog *ChatLog) (*ChatLog, error) {
+	filePath := *chatLog.FilePath
+	ext := filepath.Ext(filePath)
+	base := strings.TrimSuffix(filePath, ext) + "_branch"
+
+	branchFilePath := base + ext
+	for i := 2; fileExists(branchFilePath); i++ {
+		branchFilePath = fmt.Sprintf("%s_%d%s", base, i, ext)
+	}
+
+	branch := &ChatLog{
+		ConfigDir:   chatLog.ConfigDir,
+		ChatLogToml: chatLog.ChatLogToml,
+		FilePath:    &branchFilePath,
+		Format:      chatLog.Format,
+		OnFlush:     chatLog.OnFlush,
+	}
+	branch.ChatLogToml.Tags = append([]string(nil), chatLog.ChatLogToml.Tags...)
+	branch.ChatLogToml.Templates = append([]string(nil), chatLog.ChatLogToml.Templates...)
+	branch.ChatLogToml.Messages = append([]ChatMessage(nil), chatLog.ChatLogToml.Messages...)
+
+	if err := branch.FlushFile(); err != nil {
+		return nil, err
+	}
+
+	return branch, nil
+}
@@ -0,0 +1,135 @@
+package oax
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChatLogDirArchiveAndTrash(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "2023-04-01_12-00-00.toml")
+	chatLog := ChatLog{FilePath: &filePath, ChatLogToml: ChatLogToml{Messages: []ChatMessage{{Role: "user", Content: "hi"}}}}
+	for i := 0; i < 2; i++ {
+		if err := chatLog.FlushFile(); err != nil {
+			t.Fatalf("Error: Cannot write log: %v", err)
+		}
+	}
+
+	countLogs := func(includeArchived bool) int {
+		files, err := ListFiles(dir, ListOption{IncludeArchived: includeArchived})
+		if err != nil {
+			t.Fatalf("Error: Return err func: %v", err)
+		}
+
+		return len(files)
+	}
+
+	archived, err := ArchiveChatLog(dir, filePath)
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if countLogs(false) != 0 || countLogs(true) != 1 {
+		t.Errorf("Expected the archived log to be listed only with includeArchived")
+	}
+
+	if _, err := TrashChatLog(dir, archived); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if countLogs(true) != 0 {
+		t.Errorf("Expected the trashed log not to be listed")
+	}
+
+	restored, err := RestoreTrashedChatLog(dir)
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if restored != archived {
+		t.Errorf("Expected %q but got %q", archived, restored)
+	}
+	if _, err := os.Stat(restored + backupFileSuffix); err != nil {
+		t.Errorf("Expected the backup to move with the log: %v", err)
+	}
+
+	if _, err := RestoreTrashedChatLog(dir); err == nil {
+		t.Errorf("Expected an error for an empty trash")
+	}
+
+	resolved, err := ResolveChatLogPath(dir, "2023-04-01_12-00-00")
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if resolved != archived {
+		t.Errorf("Expected %q but got %q", archived, resolved)
+	}
+}
+
+func TestMoveChatLogKeepsFormat(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "log.toml")
+	if err := os.WriteFile(filePath, []byte("[[messages]]\n"), 0644); err != nil {
+		t.Fatalf("Error: Cannot write file: %v", err)
+	}
+
+	if err := MoveChatLog(filePath, filepath.Join(dir, "log.md")); err == nil {
+		t.Errorf("Expected an error when changing the extension")
+	}
+	if err := MoveChatLog(filePath, filepath.Join(dir, "renamed.toml")); err != nil {
+		t.Errorf("Expected a rename within the format but got %v", err)
+	}
+}
+
+func TestSetTags(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "log.md")
+	chatLog := ChatLog{FilePath: &filePath, Format: MarkdownChatLogFormat{}, ChatLogToml: ChatLogToml{Tags: []string{"go"}}}
+	if err := chatLog.FlushFile(); err != nil {
+		t.Fatalf("Error: Cannot write log: %v", err)
+	}
+
+	tags, err := SetTags(filePath, []string{"grpc", "go", "wip"}, []string{"wip"})
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+
+	loaded, err := LoadChatLog(filePath)
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if len(tags) != 2 || len(loaded.ChatLogToml.Tags) != 2 || loaded.ChatLogToml.Tags[1] != "grpc" {
+		t.Errorf("Expected [go grpc] but got %q and %q", tags, loaded.ChatLogToml.Tags)
+	}
+}
+
+func TestBranchChatLog(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "log.toml")
+	chatLog := &ChatLog{FilePath: &filePath, ChatLogToml: ChatLogToml{Model: "gpt-4", Messages: []ChatMessage{
+		{Role: "user", Content: "Hi"},
+		{Role: "assistant", Content: "Hello"},
+	}}}
+	if err := chatLog.FlushFile(); err != nil {
+		t.Fatalf("Error: Cannot write log: %v", err)
+	}
+
+	for _, expected := range []string{"log_branch.toml", "log_branch_2.toml"} {
+		branch, err := BranchChatLog(chatLog)
+		if err != nil {
+			t.Fatalf("Error: Return err func: %v", err)
+		}
+		if filepath.Base(*branch.FilePath) != expected {
+			t.Errorf("Expected %q but got %q", expected, filepath.Base(*branch.FilePath))
+		}
+
+		branch.AddChatMessage(ChatMessage{Role: "user", Content: "Branch"})
+		if len(chatLog.ChatLogToml.Messages) != 2 {
+			t.Errorf("Expected the original to keep 2 messages but got %d", len(chatLog.ChatLogToml.Messages))
+		}
+
+		loaded, err := LoadChatLog(*branch.FilePath)
+		if err != nil {
+			t.Fatalf("Error: Return err func: %v", err)
+		}
+		if loaded.ChatLogToml.Model != "gpt-4" || len(loaded.ChatLogToml.Messages) != 2 {
+			t.Errorf("Unexpected branch %+v", loaded.ChatLogToml)
+		}
+	}
+}
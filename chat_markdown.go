@@ -0,0 +1,144 @@
+package oax
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MarkdownChatLogFormat stores each message under a "## <role>" heading,
+// where the role is lowercase letters and underscores, such as user or
+// function. Lines in the content that would be read as a role heading are
+// escaped with a leading backslash. Header fields are written as front matter
+// with JSON values, which is also valid YAML.
+type MarkdownChatLogFormat struct{}
+
+var (
+	markdownRole               = regexp.MustCompile(`^[a-z_]+$`)
+	markdownRoleHeading        = regexp.MustCompile(`^## ([a-z_]+)$`)
+	markdownEscapedRoleHeading = regexp.MustCompile(`^\\+## ([a-z_]+)$`)
+	markdownFrontMatterField   = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9_]*):\s*(.*)$`)
+)
+
+const markdownFrontMatterDelimiter = "---"
+
+func (MarkdownChatLogFormat) Name() string      { return "markdown" }
+func (MarkdownChatLogFormat) Extension() string { return ".md" }
+
+func (MarkdownChatLogFormat) Marshal(chatLogToml ChatLogToml) ([]byte, error) {
+	var builder strings.Builder
+
+	if fields := chatLogToml.headerFields(); len(fields) > 0 {
+		builder.WriteString(markdownFrontMatterDelimiter + "\n")
+		for _, field := range fields {
+			value, err := json.Marshal(field.Value)
+			if err != nil {
+				return nil, err
+			}
+			builder.WriteString(fmt.Sprintf("%s: %s\n", field.Key, value))
+		}
+		builder.WriteString(markdownFrontMatterDelimiter + "\n\n")
+	}
+
+	for i, message := range chatLogToml.Messages {
+		if i > 0 {
+			builder.WriteString("\n")
+		}
+		// A role that is not a heading would be read as content of the
+		// previous message.
+		if !markdownRole.MatchString(message.Role) {
+			return nil, fmt.Errorf("message %d: role %q cannot be written to a markdown chat log", i+1, message.Role)
+		}
+		builder.WriteString("## " + message.Role + "\n\n")
+
+		for _, line := range strings.Split(message.Content, "\n") {
+			if markdownRoleHeading.MatchString(line) || markdownEscapedRoleHeading.MatchString(line) {
+				line = `\` + line
+			}
+			builder.WriteString(line + "\n")
+		}
+	}
+
+	return []byte(builder.String()), nil
+}
+
+func (MarkdownChatLogFormat) Unmarshal(data []byte) (ChatLogToml, error) {
+	var chatLogToml ChatLogToml
+	var current *ChatMessage
+	var lines []string
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		current.Content = strings.TrimLeft(strings.Join(lines, "\n"), "\n")
+		chatLogToml.Messages = append(chatLogToml.Messages, *current)
+	}
+
+	content := strings.ReplaceAll(string(data), "\r\n", "\n")
+	allLines := strings.Split(content, "\n")
+
+	if len(allLines) > 0 && allLines[0] == markdownFrontMatterDelimiter {
+		for i := 1; i < len(allLines); i++ {
+			if allLines[i] != markdownFrontMatterDelimiter {
+				continue
+			}
+
+			if err := unmarshalMarkdownFrontMatter(allLines[1:i], &chatLogToml); err != nil {
+				return ChatLogToml{}, err
+			}
+			allLines = allLines[i+1:]
+
+			break
+		}
+	}
+
+	for _, line := range allLines {
+		if match := markdownRoleHeading.FindStringSubmatch(line); match != nil {
+			flush()
+			current = &ChatMessage{Role: match[1]}
+			lines = nil
+
+			continue
+		}
+
+		if markdownEscapedRoleHeading.MatchString(line) {
+			line = line[1:]
+		}
+		lines = append(lines, line)
+	}
+	flush()
+
+	return chatLogToml, nil
+}
+
+func unmarshalMarkdownFrontMatter(lines []string, chatLogToml *ChatLogToml) error {
+	fields := map[string]json.RawMessage{}
+
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		match := markdownFrontMatterField.FindStringSubmatch(line)
+		if match == nil {
+			return fmt.Errorf("front matter line %d: expected \"key: value\"", i+2)
+		}
+
+		value := json.RawMessage(match[2])
+		if !json.Valid(value) {
+			// Allow plain YAML scalars such as `model: gpt-4` written by hand.
+			quoted, _ := json.Marshal(strings.TrimSpace(match[2]))
+			value = quoted
+		}
+		fields[match[1]] = value
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, chatLogToml)
+}
@@ -0,0 +1,149 @@
+package oax
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pelletier/go-toml"
+)
+
+// encodeChatLogToml renders messages in the layout oax has always written,
+// one [[messages]] table per message with the content on its own lines, so
+// the log stays pleasant to edit. The string form of each value is chosen
+// so that the result is always valid TOML.
+func encodeChatLogToml(chatLogToml ChatLogToml) []byte {
+	var builder strings.Builder
+
+	if fields := chatLogToml.headerFields(); len(fields) > 0 {
+		for _, field := range fields {
+			builder.WriteString(fmt.Sprintf("%s = %s\n", field.Key, tomlValue(field.Value)))
+		}
+		builder.WriteString("\n")
+	}
+
+	for _, message := range chatLogToml.Messages {
+		builder.WriteString("[[messages]]\n")
+		builder.WriteString(fmt.Sprintf("  role = %s\n", quoteTomlBasicString(message.Role)))
+		builder.WriteString(fmt.Sprintf("  content = %s\n\n", quoteTomlMultilineString(message.Content)))
+	}
+
+	return []byte(builder.String())
+}
+
+// quoteTomlMultilineString prefers a multi-line literal string, which keeps
+// content verbatim, and falls back to a multi-line basic string when the
+// content cannot be represented literally.
+func quoteTomlMultilineString(s string) string {
+	if canUseTomlMultilineLiteral(s) {
+		return "'''\n" + s + "\n'''"
+	}
+
+	var builder strings.Builder
+	builder.WriteString("\"\"\"\n")
+	for _, r := range s {
+		switch r {
+		case '\n', '\t':
+			builder.WriteRune(r)
+		case '"':
+			builder.WriteString(`\"`)
+		default:
+			writeTomlEscapedRune(&builder, r)
+		}
+	}
+	builder.WriteString("\n\"\"\"")
+
+	return builder.String()
+}
+
+func canUseTomlMultilineLiteral(s string) bool {
+	if strings.Contains(s, "'''") || !utf8.ValidString(s) {
+		return false
+	}
+
+	for _, r := range s {
+		if r != '\n' && r != '\t' && isTomlControl(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func tomlValue(value interface{}) string {
+	switch v := value.(type) {
+	case []string:
+		var quoted []string
+		for _, s := range v {
+			quoted = append(quoted, quoteTomlBasicString(s))
+		}
+
+		return "[" + strings.Join(quoted, ", ") + "]"
+	case bool:
+		return fmt.Sprint(v)
+	default:
+		return quoteTomlBasicString(fmt.Sprint(v))
+	}
+}
+
+func quoteTomlBasicString(s string) string {
+	var builder strings.Builder
+	builder.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			builder.WriteString(`\"`)
+		default:
+			writeTomlEscapedRune(&builder, r)
+		}
+	}
+	builder.WriteByte('"')
+
+	return builder.String()
+}
+
+func writeTomlEscapedRune(builder *strings.Builder, r rune) {
+	switch r {
+	case '\\':
+		builder.WriteString(`\\`)
+	case '\b':
+		builder.WriteString(`\b`)
+	case '\t':
+		builder.WriteString(`\t`)
+	case '\n':
+		builder.WriteString(`\n`)
+	case '\f':
+		builder.WriteString(`\f`)
+	case '\r':
+		builder.WriteString(`\r`)
+	default:
+		if isTomlControl(r) {
+			builder.WriteString(fmt.Sprintf(`\u%04X`, r))
+		} else {
+			builder.WriteRune(r)
+		}
+	}
+}
+
+func isTomlControl(r rune) bool {
+	return r < 0x20 || r == 0x7f
+}
+
+type TomlChatLogFormat struct{}
+
+func (TomlChatLogFormat) Name() string      { return "toml" }
+func (TomlChatLogFormat) Extension() string { return ".toml" }
+
+func (TomlChatLogFormat) Marshal(chatLogToml ChatLogToml) ([]byte, error) {
+	return encodeChatLogToml(chatLogToml), nil
+}
+
+func (TomlChatLogFormat) Unmarshal(data []byte) (ChatLogToml, error) {
+	var chatLogToml ChatLogToml
+	err := toml.Unmarshal(data, &chatLogToml)
+	if err != nil {
+		return ChatLogToml{}, err
+	}
+
+	return chatLogToml, nil
+}
@@ -0,0 +1,108 @@
+package oax
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/quick"
+	"unicode"
+)
+
+func roundTripChatLog(t *testing.T, messages []ChatMessage) []ChatMessage {
+	t.Helper()
+
+	filePath := filepath.Join(t.TempDir(), "log.toml")
+	if err := os.WriteFile(filePath, encodeChatLogToml(ChatLogToml{Messages: messages}), 0644); err != nil {
+		t.Fatalf("Error: Cannot write file: %v", err)
+	}
+
+	chatLog := ChatLog{FilePath: &filePath}
+	if err := chatLog.LoadLogMessage(); err != nil {
+		data, _ := os.ReadFile(filePath)
+		t.Fatalf("Error: Cannot load encoded log: %v\n%s", err, data)
+	}
+
+	return chatLog.ChatLogToml.Messages
+}
+
+func TestEncodeChatLogTomlRoundTrip(t *testing.T) {
+	testCases := []struct {
+		name    string
+		message ChatMessage
+	}{
+		{"plain", ChatMessage{Role: "user", Content: "Hello"}},
+		{"multi line", ChatMessage{Role: "assistant", Content: "line1\n\nline2"}},
+		{"triple single quote", ChatMessage{Role: "assistant", Content: "s = '''\nraw\n'''"}},
+		{"triple double quote", ChatMessage{Role: "assistant", Content: "'''a''' \"\"\"b\"\"\""}},
+		{"backslash", ChatMessage{Role: "user", Content: `C:\path\n ''' \u0041`}},
+		{"control", ChatMessage{Role: "user", Content: "bell\a nul\x00 del\x7f cr\r."}},
+		{"quoted role", ChatMessage{Role: `us"er\`, Content: "x"}},
+		{"leading newline", ChatMessage{Role: "user", Content: "\n\nindented"}},
+		{"trailing quote", ChatMessage{Role: "user", Content: "it's '"}},
+		{"empty", ChatMessage{Role: "", Content: ""}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := roundTripChatLog(t, []ChatMessage{tc.message})
+			if len(got) != 1 {
+				t.Fatalf("Expected 1 message but got %d", len(got))
+			}
+			if got[0] != tc.message {
+				t.Errorf("Expected %q but got %q", tc.message, got[0])
+			}
+		})
+	}
+}
+
+func TestEncodeChatLogTomlRoundTripProperty(t *testing.T) {
+	property := func(role string, contents []string) bool {
+		var messages []ChatMessage
+		for _, content := range contents {
+			messages = append(messages, ChatMessage{
+				Role:    strings.ToValidUTF8(role, ""),
+				Content: strings.TrimRightFunc(strings.ToValidUTF8(content, ""), unicode.IsSpace),
+			})
+		}
+
+		got := roundTripChatLog(t, messages)
+		if len(got) != len(messages) {
+			return false
+		}
+		for i := range messages {
+			if got[i] != messages[i] {
+				t.Logf("Expected %q but got %q", messages[i], got[i])
+				return false
+			}
+		}
+
+		return true
+	}
+
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEncodeChatLogTomlLayout(t *testing.T) {
+	got := string(encodeChatLogToml(ChatLogToml{Messages: []ChatMessage{{Role: "user", Content: "Hello"}}}))
+	expected := `[[messages]]
+  role = "user"
+  content = '''
+Hello
+'''
+
+`
+	if got != expected {
+		t.Errorf("Expected %q but got %q", expected, got)
+	}
+
+	got = string(encodeChatLogToml(ChatLogToml{Model: "gpt-4", Messages: []ChatMessage{{Role: "user", Content: "Hello"}}}))
+	expected = `model = "gpt-4"
+
+` + expected
+	if got != expected {
+		t.Errorf("Expected %q but got %q", expected, got)
+	}
+}
@@ -9,10 +9,11 @@ import (
 	"io"
 	"os"
 	"strings"
+	"unicode/utf8"
 
-	fuzzyfinder "github.com/ktr0731/go-fuzzyfinder"
 	"github.com/shuntaka9576/oax"
 	"github.com/shuntaka9576/oax/openai"
+	"golang.org/x/term"
 )
 
 type ChatOption struct {
@@ -20,12 +21,36 @@ type ChatOption struct {
 	OrganizationID string
 	Editor         string
 	Model          string
+	// Params and ContextSize come from the model alias, if any.
+	Params         oax.ModelParams
+	ContextSize    int
 	Role           string
 	ChatLogDir     string
+	ChatLogFormat  string
 	FileNameFormat string
 	File           *string
 	Continue       bool
+	Archived       bool
+	ListOption     oax.ListOption
+	Search         string
+	Semantic       bool
+	SemanticIndex  bool
+	EmbeddingModel string
+	DataDir        string
 	Template       *oax.ChatTemplate
+	// TemplateMode is how Template is applied to a resumed conversation,
+	// oax.TemplateModeSystem or oax.TemplateModeAppend.
+	TemplateMode string
+	// Vars are the template variables given with --var.
+	Vars map[string]string
+	// TUI runs the chat in a full-screen terminal UI instead of the editor.
+	TUI bool
+	// ModelChoices are the models the TUI can switch to.
+	ModelChoices []oax.ResolvedModel
+	// Render is oax.RenderMarkdown or oax.RenderRaw, and Theme the style of
+	// highlighted code blocks.
+	Render string
+	Theme  string
 }
 
 var (
@@ -34,19 +59,12 @@ var (
 
 func Chat(opt *ChatOption) error {
 	if opt.Continue {
-		files, err := oax.ListFiles(opt.ChatLogDir)
-		if err != nil {
-			return nil
-		}
-
-		index, err := fuzzyfinder.Find(files, func(i int) string {
-			return files[i].FileName
-		})
+		filePath, err := pickChatLogFile(opt)
 		if err != nil {
 			return err
 		}
 
-		opt.File = &files[index].FileFullPath
+		opt.File = &filePath
 	}
 
 	if opt.Role == "" {
@@ -58,9 +76,49 @@ func Chat(opt *ChatOption) error {
 		Content: contentUserDefault,
 	}
 
+	chatLogFormat, err := oax.ChatLogFormatByName(opt.ChatLogFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s. Please check settings using `oax config --settings`.\n", err)
+
+		return err
+	}
+
 	chatLog := oax.ChatLog{
 		ConfigDir:   opt.ChatLogDir,
 		ChatLogToml: oax.ChatLogToml{},
+		Format:      chatLogFormat,
+	}
+
+	if opt.SemanticIndex {
+		// Embedding calls the API, so the chat logs that got an answer are
+		// indexed once the chat ends, after they are unlocked, rather than
+		// each time they are written.
+		answered := map[string]bool{}
+		chatLog.OnFlush = func(c *oax.ChatLog) error {
+			messages := c.ChatLogToml.Messages
+			if len(messages) > 0 && messages[len(messages)-1].Role == "assistant" {
+				answered[*c.FilePath] = true
+			}
+
+			return nil
+		}
+		defer updateVectorIndex(opt, answered)
+	}
+
+	var templateMessages []oax.Message
+	if opt.Template != nil {
+		templateMessages, err = opt.Template.Render(&oax.RenderTemplateOption{
+			Vars:   opt.Vars,
+			Prompt: promptTemplateParam,
+			Warn: func(message string) {
+				fmt.Fprintf(os.Stderr, "warning: %s\n", message)
+			},
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+
+			return err
+		}
 	}
 
 	if opt.File == nil {
@@ -74,30 +132,66 @@ func Chat(opt *ChatOption) error {
 
 		title = title[:len(title)-1]
 		chatLog.InitLogFile(title, opt.FileNameFormat)
+		if err := chatLog.Lock(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+
+			return err
+		}
+		defer chatLog.Unlock()
+
 		chatLog.FlushFile()
 	} else {
 		if err := chatLog.LoadFile(*opt.File); err != nil {
 			return err
 		}
+		if err := chatLog.Lock(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+
+			return err
+		}
+		defer chatLog.Unlock()
+
 		err := chatLog.LoadLogMessage()
 		if err != nil {
 			return err
 		}
 	}
 
-	if !isLastEmptyMessage(chatLog.ChatLogToml.Messages) {
-		if opt.File == nil && opt.Template != nil {
-			for _, message := range opt.Template.Messages {
-				chatLog.AddChatMessage(
-					oax.ChatMessage(message),
-				)
-			}
+	// A template appended to a resumed conversation is its next turn, which
+	// can still be edited before it is sent.
+	appendedTurn := false
+	if opt.Template != nil {
+		mode := oax.TemplateModeNew
+		if opt.File != nil {
+			mode = opt.TemplateMode
+		}
+
+		messages := chatLog.ChatLogToml.Messages
+		if mode == oax.TemplateModeAppend && isLastEmptyMessage(messages) {
+			chatLog.ChatLogToml.Messages = messages[:len(messages)-1]
+		}
+
+		if err := chatLog.ApplyTemplate(opt.Template.Name, templateMessages, mode); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+
+			return err
+		}
+		appendedTurn = mode == oax.TemplateModeAppend
+	}
+
+	if opt.TUI {
+		if err := runChatTUI(&chatLog, opt); err != nil {
+			return err
 		}
 
+		return finishChatTUI(chatLog, opt)
+	}
+
+	if !appendedTurn && !isLastEmptyMessage(chatLog.ChatLogToml.Messages) {
 		chatLog.AddChatMessage(userEmptyMessage)
 	}
 
-	err := chatLog.FlushFile()
+	err = chatLog.FlushFile()
 	if err != nil {
 		return err
 	}
@@ -138,7 +232,8 @@ func Chat(opt *ChatOption) error {
 
 	bufFromChatGPT := bytes.Buffer{}
 	chatGPTChatMessage := oax.ChatMessage{}
-	multiWriter := io.MultiWriter(&bufFromChatGPT, os.Stdout)
+	output, flushOutput := chatOutput(opt)
+	multiWriter := io.MultiWriter(&bufFromChatGPT, output)
 
 	ctx := context.Background()
 
@@ -172,16 +267,21 @@ LOOP:
 	INTERACTIVE:
 		for {
 			if isSkip := isLastEmptyMessage(chatLog.ChatLogToml.Messages); !isSkip {
+				warnContextSize(&chatLog, opt)
+
 				err := openaiClient.ChatCreateCompletionSubscribeWithContext(ctx, &openai.ChatCreateCompletionOption{
 					Messages: chatLog.CreateOpenAIMessages(),
 					Model:    opt.Model,
+					Params:   chatParams(opt.Params),
 				}, subScribeChat)
+				flushOutput()
 
 				if err != nil {
 					return err
 				}
 
 				chatGPTChatMessage.Content = bufFromChatGPT.String()
+				chatLog.ChatLogToml.Model = opt.Model
 				chatLog.AddChatMessage(chatGPTChatMessage)
 				chatLog.FlushFile()
 			} else {
@@ -263,6 +363,75 @@ func deleteFile(chatLog oax.ChatLog) error {
 	return nil
 }
 
+func chatParams(params oax.ModelParams) openai.ChatParams {
+	return openai.ChatParams{
+		Temperature:      params.Temperature,
+		TopP:             params.TopP,
+		MaxTokens:        params.MaxTokens,
+		PresencePenalty:  params.PresencePenalty,
+		FrequencyPenalty: params.FrequencyPenalty,
+	}
+}
+
+// warnContextSize warns when the conversation is likely larger than the
+// context size of the model.
+func warnContextSize(chatLog *oax.ChatLog, opt *ChatOption) {
+	if warning := contextSizeWarning(chatLog, opt); warning != "" {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+}
+
+// contextSizeWarning estimates four characters, not bytes, per token.
+func contextSizeWarning(chatLog *oax.ChatLog, opt *ChatOption) string {
+	if opt.ContextSize <= 0 {
+		return ""
+	}
+
+	chars := 0
+	for _, message := range chatLog.ChatLogToml.Messages {
+		chars += utf8.RuneCountInString(message.Content)
+	}
+
+	if tokens := chars / 4; tokens > opt.ContextSize {
+		return fmt.Sprintf("the conversation is about %d tokens, more than the context size %d of %s", tokens, opt.ContextSize, opt.Model)
+	}
+
+	return ""
+}
+
+// chatOutput returns the writer answers are streamed to and a function to
+// call when an answer is complete. Markdown is rendered only when stdout is
+// a terminal.
+func chatOutput(opt *ChatOption) (io.Writer, func()) {
+	if opt.Render != oax.RenderMarkdown || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return os.Stdout, func() {}
+	}
+
+	renderer := oax.NewMarkdownRenderer(os.Stdout, opt.Theme)
+
+	return renderer, func() { renderer.Flush() }
+}
+
+// updateVectorIndex embeds the new messages of the chat logs at filePaths.
+// A chat log that was removed or renamed since is skipped.
+func updateVectorIndex(opt *ChatOption, filePaths map[string]bool) {
+	if len(filePaths) == 0 {
+		return
+	}
+
+	vectorIndex := newVectorIndex(opt.DataDir, opt.ChatLogDir, opt.APIKey, opt.OrganizationID, opt.EmbeddingModel)
+	for filePath := range filePaths {
+		chatLog, err := oax.LoadChatLog(filePath)
+		if err != nil {
+			continue
+		}
+
+		if err := vectorIndex.UpdateChatLog(context.Background(), chatLog); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to update the semantic search index: %s\n", err)
+		}
+	}
+}
+
 func isLastEmptyMessage(messages []oax.ChatMessage) bool {
 	if len(messages) > 0 {
 		lastmsg := messages[len(messages)-1]
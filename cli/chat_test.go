@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shuntaka9576/oax"
+)
+
+func TestContextSizeWarning(t *testing.T) {
+	testCases := []struct {
+		name        string
+		content     string
+		contextSize int
+		expected    bool
+	}{
+		{"within", strings.Repeat("a", 40), 10, false},
+		{"over", strings.Repeat("a", 44), 10, true},
+		{"multibyte characters", strings.Repeat("あ", 40), 10, false},
+		{"no context size", strings.Repeat("a", 400), 0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			chatLog := &oax.ChatLog{}
+			chatLog.AddChatMessage(oax.ChatMessage{Role: "user", Content: tc.content})
+
+			warning := contextSizeWarning(chatLog, &ChatOption{ContextSize: tc.contextSize, Model: "gpt-4"})
+			if (warning != "") != tc.expected {
+				t.Errorf("Expected a warning %v but got %q", tc.expected, warning)
+			}
+		})
+	}
+}
@@ -24,3 +24,23 @@ func (v VersionFlag) BeforeApply(app *kong.Kong, vars kong.Vars) error {
 
 	return nil
 }
+
+// TemplateFlag is a flag with an optional value: -t NAME selects the
+// template NAME, and -t alone selects one with fuzzy matching.
+type TemplateFlag struct {
+	Name string
+	Pick bool
+}
+
+func (t *TemplateFlag) Decode(ctx *kong.DecodeContext) error {
+	if !ctx.Scan.Peek().IsValue() {
+		t.Pick = true
+
+		return nil
+	}
+
+	token := ctx.Scan.Pop()
+	t.Name = token.String()
+
+	return nil
+}
@@ -0,0 +1,326 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/kong"
+	"github.com/shuntaka9576/oax"
+)
+
+// CompleteCommand is the hidden command the completion scripts run to get
+// the candidates for the word being completed.
+const CompleteCommand = "__complete"
+
+var completionScripts = map[string]string{
+	"bash": `# bash completion for oax
+_oax() {
+    local IFS=$'\n'
+    COMPREPLY=($(oax ` + CompleteCommand + ` "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _oax oax
+`,
+	"zsh": `#compdef oax
+# zsh completion for oax
+_oax() {
+    local -a candidates
+    candidates=("${(@f)$(oax ` + CompleteCommand + ` "${words[@]:1:$((CURRENT-1))}" 2>/dev/null)}")
+    if [[ -n "${candidates[1]}" ]]; then
+        compadd -- "${candidates[@]}"
+    else
+        _files
+    fi
+}
+compdef _oax oax
+`,
+	"fish": `# fish completion for oax
+complete -c oax -f -a '(oax ` + CompleteCommand + ` (commandline -opc)[2..-1] (commandline -ct) 2>/dev/null)'
+`,
+}
+
+// CompletionShells lists the shells oax completion supports.
+func CompletionShells() []string {
+	var shells []string
+	for shell := range completionScripts {
+		shells = append(shells, shell)
+	}
+	sort.Strings(shells)
+
+	return shells
+}
+
+// Completion prints the completion script for shell.
+func Completion(shell string) error {
+	script, ok := completionScripts[shell]
+	if !ok {
+		err := fmt.Errorf("unsupported shell %s (supported: %s)", shell, strings.Join(CompletionShells(), ", "))
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	fmt.Print(script)
+
+	return nil
+}
+
+// Complete prints the candidates for the last of args, which are the words
+// of the command line after the program name. config may be nil when the
+// configuration cannot be loaded, in which case only static candidates are
+// printed.
+func Complete(app *kong.Application, config *oax.Config, args []string) {
+	for _, candidate := range completionCandidates(app, config, args) {
+		fmt.Println(candidate)
+	}
+}
+
+func completionCandidates(app *kong.Application, config *oax.Config, args []string) []string {
+	current := ""
+	if len(args) > 0 {
+		current = args[len(args)-1]
+		args = args[:len(args)-1]
+	}
+	// bash splits --flag=value into three words.
+	if current == "=" {
+		current = ""
+	}
+
+	node := app.Node
+	var pendingFlag *kong.Flag
+	position := 0
+
+	for _, word := range args {
+		if word == "=" {
+			continue
+		}
+		if pendingFlag != nil {
+			pendingFlag = nil
+
+			continue
+		}
+
+		if strings.HasPrefix(word, "-") && word != "-" {
+			if flag := findCompletionFlag(node, word); flag != nil && !flag.IsBool() && !strings.Contains(word, "=") {
+				pendingFlag = flag
+			}
+
+			continue
+		}
+
+		if child := findCompletionChild(node, word); child != nil {
+			node = child
+			position = 0
+
+			continue
+		}
+
+		position++
+	}
+
+	var candidates []string
+
+	switch {
+	case pendingFlag != nil:
+		candidates = flagCompletions(pendingFlag, config)
+	case strings.HasPrefix(current, "--") && strings.Contains(current, "="):
+		name, _, _ := strings.Cut(current, "=")
+		if flag := findCompletionFlag(node, name); flag != nil {
+			for _, value := range flagCompletions(flag, config) {
+				candidates = append(candidates, name+"="+value)
+			}
+		}
+	case strings.HasPrefix(current, "-"):
+		for n := node; n != nil; n = n.Parent {
+			for _, flag := range n.Flags {
+				if flag.Hidden {
+					continue
+				}
+				candidates = append(candidates, "--"+flag.Name)
+				if flag.Short != 0 {
+					candidates = append(candidates, "-"+string(flag.Short))
+				}
+			}
+		}
+		candidates = append(candidates, "--help")
+	default:
+		for _, child := range node.Children {
+			if !child.Hidden {
+				candidates = append(candidates, child.Name)
+			}
+		}
+
+		if len(node.Positional) > 0 {
+			if position >= len(node.Positional) {
+				position = len(node.Positional) - 1
+				if !node.Positional[position].IsCumulative() {
+					break
+				}
+			}
+			candidates = append(candidates, positionalCompletions(node, node.Positional[position], config)...)
+		}
+	}
+
+	return filterByPrefix(candidates, current)
+}
+
+func findCompletionFlag(node *kong.Node, word string) *kong.Flag {
+	name, _, _ := strings.Cut(word, "=")
+
+	for n := node; n != nil; n = n.Parent {
+		for _, flag := range n.Flags {
+			if name == "--"+flag.Name || (flag.Short != 0 && name == "-"+string(flag.Short)) {
+				return flag
+			}
+		}
+	}
+
+	return nil
+}
+
+func findCompletionChild(node *kong.Node, word string) *kong.Node {
+	for _, child := range node.Children {
+		if child.Name == word {
+			return child
+		}
+		for _, alias := range child.Aliases {
+			if alias == word {
+				return child
+			}
+		}
+	}
+
+	return nil
+}
+
+func flagCompletions(flag *kong.Flag, config *oax.Config) []string {
+	if flag.Enum != "" {
+		return flag.EnumSlice()
+	}
+
+	if config == nil {
+		return nil
+	}
+
+	switch flag.Name {
+	case "profile":
+		return profileNames(config)
+	case "template-name":
+		return templateNames(config)
+	case "model":
+		return modelNames(config)
+	case "file":
+		return chatLogCompletions(config, true)
+	default:
+		return nil
+	}
+}
+
+func positionalCompletions(node *kong.Node, positional *kong.Positional, config *oax.Config) []string {
+	if positional.Enum != "" {
+		return positional.EnumSlice()
+	}
+
+	if config == nil {
+		return nil
+	}
+
+	switch {
+	case node.Parent != nil && node.Parent.Name == "log" && (positional.Name == "files" || positional.Name == "file"):
+		return chatLogCompletions(config, false)
+	case node.Name == "export" && positional.Name == "files":
+		return chatLogCompletions(config, true)
+	case node.Parent != nil && node.Parent.Name == "profile" && node.Name != "add" && positional.Name == "name":
+		return profileNames(config)
+	case node.Name == "store-key" && positional.Name == "profile":
+		return profileNames(config)
+	case node.Parent != nil && node.Parent.Name == "template" && node.Name != "new" && positional.Name == "name":
+		return templateNames(config)
+	default:
+		return nil
+	}
+}
+
+func profileNames(config *oax.Config) []string {
+	var names []string
+	for _, profile := range config.Profiles {
+		names = append(names, profile.Name)
+	}
+
+	return names
+}
+
+func templateNames(config *oax.Config) []string {
+	var names []string
+	for _, template := range config.Settings.Chat.Templates {
+		names = append(names, template.Name)
+	}
+
+	return names
+}
+
+// modelNames lists the model aliases and the models cached by oax profile
+// test and oax models.
+func modelNames(config *oax.Config) []string {
+	names := config.Settings.ModelAliasNames()
+
+	cache, err := oax.OpenModelCache(config.ConfigDir)
+	if err != nil {
+		return names
+	}
+
+	seen := map[string]bool{}
+	var models []string
+	for _, profile := range cache.Profiles {
+		for _, model := range profile.Models {
+			if !seen[model] && oax.IsChatModel(model) {
+				seen[model] = true
+				models = append(models, model)
+			}
+		}
+	}
+	sort.Strings(models)
+
+	return append(names, models...)
+}
+
+// chatLogCompletions lists chat logs as full paths, or as paths relative
+// to the chat log directory, which the log commands accept.
+func chatLogCompletions(config *oax.Config, fullPath bool) []string {
+	chatLogDir := config.Settings.Setting.ChatLogDir
+
+	files, err := oax.ListFiles(chatLogDir, config.Settings.Setting.ListOption())
+	if err != nil {
+		return nil
+	}
+
+	var paths []string
+	for _, file := range files {
+		if fullPath {
+			paths = append(paths, file.FileFullPath)
+
+			continue
+		}
+
+		rel, err := filepath.Rel(chatLogDir, file.FileFullPath)
+		if err != nil {
+			continue
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+	}
+
+	return paths
+}
+
+func filterByPrefix(candidates []string, prefix string) []string {
+	var filtered []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, prefix) {
+			filtered = append(filtered, candidate)
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/alecthomas/kong"
+	"github.com/shuntaka9576/oax"
+)
+
+type completionTestCLI struct {
+	Profile string `short:"p"`
+
+	Chat struct {
+		Model        string  `short:"m"`
+		File         *string `short:"f"`
+		TemplateName string  `short:"t"`
+		Format       string  `enum:"toml,markdown" default:"toml"`
+		Continue     bool    `short:"c"`
+	} `cmd:""`
+
+	Log struct {
+		Rm struct {
+			Files []string `arg:"" optional:""`
+		} `cmd:""`
+		Rename struct {
+			File string `arg:""`
+			Name string `arg:""`
+		} `cmd:""`
+	} `cmd:""`
+
+	ProfileCmd struct {
+		Show struct {
+			Name string `arg:""`
+		} `cmd:""`
+		Add struct {
+			Name string `arg:""`
+		} `cmd:""`
+	} `cmd:"" name:"profile"`
+}
+
+func TestCompletionCandidates(t *testing.T) {
+	parser, err := kong.New(&completionTestCLI{})
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+
+	configDir := t.TempDir()
+	chatLogDir := filepath.Join(configDir, "chat-log")
+	if err := os.Mkdir(chatLogDir, 0755); err != nil {
+		t.Fatalf("Error: Cannot create dir: %v", err)
+	}
+	for _, name := range []string{"a.toml", "b.toml"} {
+		if err := os.WriteFile(filepath.Join(chatLogDir, name), nil, 0644); err != nil {
+			t.Fatalf("Error: Cannot write file: %v", err)
+		}
+	}
+
+	cache, err := oax.OpenModelCache(configDir)
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	cache.Set("me", []string{"gpt-4", "text-embedding-ada-002"})
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+
+	config := &oax.Config{
+		ConfigDir: configDir,
+		Profiles:  []oax.Profile{{Name: "me"}, {Name: "work"}},
+		Settings: oax.Settings{
+			Setting: oax.Setting{ChatLogDir: chatLogDir, ChatLogSort: oax.SortByTitle},
+			Chat:    oax.Chat{Templates: []oax.ChatTemplate{{Name: "reviewer"}}},
+			Models:  map[string]oax.ModelAlias{"smart": {Model: "gpt-4"}},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{"subcommands", []string{"l"}, []string{"log"}},
+		{"nested subcommands", []string{"log", "r"}, []string{"rm", "rename"}},
+		{"flags", []string{"chat", "--m"}, []string{"--model"}},
+		{"flags of parents", []string{"chat", "--p"}, []string{"--profile"}},
+		{"short flags", []string{"chat", "-c"}, []string{"-c"}},
+		{"profile", []string{"--profile", ""}, []string{"me", "work"}},
+		{"short profile", []string{"chat", "-p", "w"}, []string{"work"}},
+		{"model", []string{"chat", "--model", ""}, []string{"smart", "gpt-4"}},
+		{"model with equals", []string{"chat", "--model=s"}, []string{"--model=smart"}},
+		{"model split by bash", []string{"chat", "--model", "=", "g"}, []string{"gpt-4"}},
+		{"template", []string{"chat", "-t", ""}, []string{"reviewer"}},
+		{"enum", []string{"chat", "--format", ""}, []string{"toml", "markdown"}},
+		{"bool flag takes no value", []string{"chat", "--continue", ""}, nil},
+		{"file", []string{"chat", "--file", ""}, []string{filepath.Join(chatLogDir, "a.toml"), filepath.Join(chatLogDir, "b.toml")}},
+		{"log rm files", []string{"log", "rm", ""}, []string{"a.toml", "b.toml"}},
+		{"log rm more files", []string{"log", "rm", "a.toml", "b"}, []string{"b.toml"}},
+		{"second positional", []string{"log", "rename", "a.toml", ""}, nil},
+		{"profile name", []string{"profile", "show", ""}, []string{"me", "work"}},
+		{"new profile name", []string{"profile", "add", ""}, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := completionCandidates(parser.Model, config, tc.args)
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("Expected %q but got %q", tc.expected, result)
+			}
+		})
+	}
+
+	if result := completionCandidates(parser.Model, nil, []string{"--profile", ""}); result != nil {
+		t.Errorf("Expected no candidates without a configuration but got %q", result)
+	}
+}
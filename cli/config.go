@@ -1,6 +1,10 @@
 package cli
 
 import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
 	"github.com/shuntaka9576/oax"
 )
 
@@ -12,3 +16,101 @@ func Config(editor string, setting bool, profile bool) error {
 
 	return nil
 }
+
+// ConfigShow prints the effective configuration, and with resolved where
+// each value came from.
+func ConfigShow(config *oax.Config, resolved bool) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	if resolved {
+		fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+	}
+
+	for _, value := range config.Resolved() {
+		if resolved {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", value.Key, value.Value, value.Source)
+		} else {
+			fmt.Fprintf(w, "%s\t%s\n", value.Key, value.Value)
+		}
+	}
+
+	err := w.Flush()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	return nil
+}
+
+// ConfigValidate prints the problems found in the configuration files and
+// fails when there are any.
+func ConfigValidate(opt *oax.ConfigOption) error {
+	diagnostics, err := oax.ValidateConfig(opt)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	for _, diagnostic := range diagnostics {
+		fmt.Println(diagnostic)
+	}
+
+	if len(diagnostics) > 0 {
+		err := fmt.Errorf("found %d problems", len(diagnostics))
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	fmt.Println("configuration is valid")
+
+	return nil
+}
+
+// ConfigStoreKey saves the API key of a profile in the encrypted key store,
+// or removes it with remove.
+func ConfigStoreKey(config *oax.Config, profileName string, remove bool) error {
+	keyStore := config.KeyStore()
+
+	if remove {
+		err := keyStore.Delete(profileName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+
+			return err
+		}
+		fmt.Printf("removed the API key of profile %s from %s\n", profileName, keyStore.FilePath)
+
+		return nil
+	}
+
+	apiKey, err := oax.ReadSecret(fmt.Sprintf("API key for profile %s: ", profileName))
+	if err == nil && apiKey == "" {
+		err = fmt.Errorf("empty API key")
+	}
+	if err == nil {
+		err = keyStore.Set(profileName, apiKey)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	fmt.Printf("saved the API key of profile %s to %s\n", profileName, keyStore.FilePath)
+
+	usesKeyStore := false
+	for _, profile := range config.Profiles {
+		if profile.Name == profileName && profile.ApiKeyStore {
+			usesKeyStore = true
+		}
+	}
+	if !usesKeyStore {
+		fmt.Printf("set apiKeyStore = true in profile %s to use it\n", profileName)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	fuzzyfinder "github.com/ktr0731/go-fuzzyfinder"
+	"github.com/shuntaka9576/oax"
+)
+
+type ExportOption struct {
+	ChatLogDir string
+	Files      []string
+	Format     string
+	Roles      []string
+	Output     string
+	ListOption oax.ListOption
+}
+
+func Export(opt *ExportOption) error {
+	files := opt.Files
+
+	if len(files) == 0 {
+		fileInfos, err := oax.ListFiles(opt.ChatLogDir, opt.ListOption)
+		if err != nil {
+			return err
+		}
+
+		indexes, err := fuzzyfinder.FindMulti(fileInfos, func(i int) string {
+			return pickerItemTitle(fileInfos[i])
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, index := range indexes {
+			files = append(files, fileInfos[index].FileFullPath)
+		}
+	}
+
+	var conversations []oax.ExportConversation
+	for _, file := range files {
+		chatLog, err := oax.LoadChatLog(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+
+			return err
+		}
+
+		if isLastEmptyMessage(chatLog.ChatLogToml.Messages) {
+			chatLog.ChatLogToml.Messages = chatLog.ChatLogToml.Messages[:len(chatLog.ChatLogToml.Messages)-1]
+		}
+
+		conversations = append(conversations, oax.NewExportConversation(chatLog, opt.Roles))
+	}
+
+	if opt.Output == "" {
+		err := oax.Export(os.Stdout, conversations, opt.Format)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+
+			return err
+		}
+
+		return nil
+	}
+
+	if err := exportFile(opt.Output, conversations, opt.Format); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	return nil
+}
+
+// exportFile writes the export to filePath. Errors from flushing and
+// closing the file are returned so that a failed write is not reported as
+// success.
+func exportFile(filePath string, conversations []oax.ExportConversation, format string) error {
+	f, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(f)
+	if err := oax.Export(bw, conversations, format); err != nil {
+		f.Close()
+
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+
+		return err
+	}
+
+	return f.Close()
+}
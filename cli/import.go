@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shuntaka9576/oax"
+)
+
+type ImportOption struct {
+	ChatLogDir     string
+	ChatLogFormat  string
+	FileNameFormat string
+	Files          []string
+	Format         string
+	DryRun         bool
+}
+
+func Import(opt *ImportOption) error {
+	chatLogFormat, err := oax.ChatLogFormatByName(opt.ChatLogFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s. Please check settings using `oax config --settings`.\n", err)
+
+		return err
+	}
+
+	imported := importedSources(opt.ChatLogDir)
+
+	for _, file := range opt.Files {
+		conversations, err := readImportFile(file, opt.Format)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", file, err)
+
+			return err
+		}
+
+		for _, conversation := range conversations {
+			if filePath, ok := imported[conversation.Source]; ok {
+				fmt.Fprintf(os.Stderr, "already imported: %s\n", filePath)
+
+				continue
+			}
+
+			chatLog := oax.ChatLog{
+				ConfigDir: opt.ChatLogDir,
+				Format:    chatLogFormat,
+			}
+
+			createTime := conversation.CreateTime
+			if createTime.IsZero() {
+				createTime = time.Now()
+			}
+			chatLog.InitLogFileAt(conversation.Title, opt.FileNameFormat, createTime)
+
+			filePathForUser, err := chatLog.FilePathForUser()
+			if err != nil {
+				return err
+			}
+
+			if opt.DryRun {
+				fmt.Printf("%s (%d messages)\n", filePathForUser, len(conversation.Messages))
+
+				continue
+			}
+
+			chatLog.ChatLogToml.Source = conversation.Source
+			for _, message := range conversation.Messages {
+				chatLog.AddChatMessage(message)
+			}
+			if err := chatLog.FlushFile(); err != nil {
+				fmt.Fprintf(os.Stderr, "%s\n", err)
+
+				return err
+			}
+			imported[conversation.Source] = filePathForUser
+
+			if !conversation.UpdateTime.IsZero() {
+				if err := os.Chtimes(*chatLog.FilePath, conversation.UpdateTime, conversation.UpdateTime); err != nil {
+					fmt.Fprintf(os.Stderr, "%s\n", err)
+
+					return err
+				}
+			}
+
+			fmt.Fprintf(os.Stderr, "imported: %s\n", filePathForUser)
+		}
+	}
+
+	return nil
+}
+
+// importedSources maps the source of each chat log imported into
+// chatLogDir to its path. Chat logs that cannot be read are left out.
+func importedSources(chatLogDir string) map[string]string {
+	imported := map[string]string{}
+
+	files, err := oax.ListFiles(chatLogDir, oax.ListOption{Recursive: true})
+	if err != nil {
+		return imported
+	}
+
+	for _, file := range files {
+		chatLog, err := oax.LoadChatLog(file.FileFullPath)
+		if err != nil || chatLog.ChatLogToml.Source == "" {
+			continue
+		}
+		imported[chatLog.ChatLogToml.Source] = file.FileFullPath
+	}
+
+	return imported
+}
+
+// readImportFile also accepts the zip archive downloaded from the ChatGPT
+// data export and reads conversations.json from it.
+func readImportFile(file string, format string) ([]oax.ImportedConversation, error) {
+	if strings.EqualFold(filepath.Ext(file), ".zip") {
+		archive, err := zip.OpenReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer archive.Close()
+
+		for _, f := range archive.File {
+			if filepath.Base(f.Name) != "conversations.json" {
+				continue
+			}
+
+			r, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer r.Close()
+
+			if format == "" {
+				format = oax.ImportFormatChatGPT
+			}
+
+			return oax.ParseImport(r, format)
+		}
+
+		return nil, fmt.Errorf("conversations.json not found in archive")
+	}
+
+	var r io.Reader
+	if file == "-" {
+		r = os.Stdin
+	} else {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	return oax.ParseImport(r, format)
+}
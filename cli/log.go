@@ -0,0 +1,272 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/shuntaka9576/oax"
+)
+
+type LogOption struct {
+	ChatLogDir string
+	DataDir    string
+	// Files are chat log paths or names relative to ChatLogDir. The user
+	// picks one with fuzzy matching when it is empty.
+	Files      []string
+	Archived   bool
+	Tags       []string
+	ListOption oax.ListOption
+}
+
+func LogList(opt *LogOption) error {
+	listOption := opt.ListOption
+	listOption.IncludeArchived = opt.Archived
+
+	files, err := oax.ListFiles(opt.ChatLogDir, listOption)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	index, err := oax.UpdateSearchIndex(searchIndexPath(opt.DataDir, opt.ChatLogDir), opt.ChatLogDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TITLE\tDATE\tMODEL\tTURNS\tTAGS")
+
+	for _, file := range files {
+		entry := index.Entries[file.FileFullPath]
+		if !hasAllTags(entry.Tags, opt.Tags) {
+			continue
+		}
+
+		title := file.Title
+		if file.Group != "" {
+			title = file.Group + "/" + title
+		}
+
+		model := entry.Model
+		if model == "" {
+			model = "-"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n",
+			title,
+			entry.ModTime.Format("2006-01-02 15:04"),
+			model,
+			countTurns(entry.Messages),
+			strings.Join(entry.Tags, ","),
+		)
+	}
+
+	return w.Flush()
+}
+
+func LogShow(opt *LogOption) error {
+	filePaths, err := resolveLogFiles(opt)
+	if err != nil {
+		return err
+	}
+
+	var conversations []oax.ExportConversation
+	for _, filePath := range filePaths {
+		chatLog, err := oax.LoadChatLog(filePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+
+			return err
+		}
+		conversations = append(conversations, oax.NewExportConversation(chatLog, nil))
+	}
+
+	return oax.Export(os.Stdout, conversations, oax.ExportFormatMarkdown)
+}
+
+func LogRename(opt *LogOption, name string) error {
+	filePaths, err := resolveLogFiles(opt)
+	if err != nil {
+		return err
+	}
+	filePath := filePaths[0]
+
+	if name != filepath.Base(name) {
+		err := fmt.Errorf("the new name must not contain a directory: %s", name)
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+	if !oax.IsChatLogFile(name) {
+		name += filepath.Ext(filePath)
+	}
+
+	newFilePath := filepath.Join(filepath.Dir(filePath), name)
+	if err := oax.MoveChatLog(filePath, newFilePath); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	return printLogAction("renamed", newFilePath)
+}
+
+func LogRemove(opt *LogOption) error {
+	filePaths, err := resolveLogFiles(opt)
+	if err != nil {
+		return err
+	}
+
+	for _, filePath := range filePaths {
+		if _, err := oax.TrashChatLog(opt.ChatLogDir, filePath); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+
+			return err
+		}
+
+		if err := printLogAction("moved to trash", filePath); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(os.Stderr, "run `oax log undo` to restore.\n")
+
+	return nil
+}
+
+func LogUndo(opt *LogOption) error {
+	filePath, err := oax.RestoreTrashedChatLog(opt.ChatLogDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	return printLogAction("restored", filePath)
+}
+
+func LogArchive(opt *LogOption, restore bool) error {
+	// Picking a log to restore must include the archive.
+	opt.Archived = opt.Archived || restore
+
+	filePaths, err := resolveLogFiles(opt)
+	if err != nil {
+		return err
+	}
+
+	for _, filePath := range filePaths {
+		var newFilePath string
+		var action string
+
+		if restore {
+			newFilePath, err = oax.UnarchiveChatLog(opt.ChatLogDir, filePath)
+			action = "unarchived"
+		} else {
+			newFilePath, err = oax.ArchiveChatLog(opt.ChatLogDir, filePath)
+			action = "archived"
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+
+			return err
+		}
+
+		if err := printLogAction(action, newFilePath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func LogTag(opt *LogOption, add []string, remove []string) error {
+	filePaths, err := resolveLogFiles(opt)
+	if err != nil {
+		return err
+	}
+
+	tags, err := oax.SetTags(filePaths[0], add, remove)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	fmt.Println(strings.Join(tags, ","))
+
+	return nil
+}
+
+func resolveLogFiles(opt *LogOption) ([]string, error) {
+	if len(opt.Files) == 0 {
+		filePath, err := pickChatLogFile(&ChatOption{
+			ChatLogDir: opt.ChatLogDir,
+			DataDir:    opt.DataDir,
+			Archived:   opt.Archived,
+			ListOption: opt.ListOption,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return []string{filePath}, nil
+	}
+
+	var filePaths []string
+	for _, file := range opt.Files {
+		filePath, err := oax.ResolveChatLogPath(opt.ChatLogDir, file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+
+			return nil, err
+		}
+		filePaths = append(filePaths, filePath)
+	}
+
+	return filePaths, nil
+}
+
+func printLogAction(action string, filePath string) error {
+	chatLog := oax.ChatLog{FilePath: &filePath}
+	filePathForUser, err := chatLog.FilePathForUser()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%s: %s\n", action, filePathForUser)
+
+	return nil
+}
+
+func countTurns(messages []oax.ChatMessage) int {
+	turns := 0
+	for _, message := range messages {
+		if message.Role == "user" && message.Content != contentUserDefault {
+			turns++
+		}
+	}
+
+	return turns
+}
+
+func hasAllTags(tags []string, required []string) bool {
+	for _, r := range required {
+		found := false
+		for _, tag := range tags {
+			if tag == r {
+				found = true
+
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
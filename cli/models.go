@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/shuntaka9576/oax"
+	"github.com/shuntaka9576/oax/openai"
+)
+
+type ModelsOption struct {
+	APIKey         string
+	OrganizationID string
+	ProfileName    string
+	ConfigDir      string
+	Aliases        map[string]oax.ModelAlias
+	// All lists every model instead of only chat models.
+	All bool
+	// AliasesOnly lists the model aliases without calling the API.
+	AliasesOnly bool
+}
+
+// Models lists the models of the provider with the aliases that refer to
+// them, and caches the chat models of the profile.
+func Models(opt *ModelsOption) error {
+	if opt.AliasesOnly {
+		return printModelAliases(opt.Aliases)
+	}
+
+	client := openai.InitClient(&openai.InitClientOptions{
+		APIKey:         opt.APIKey,
+		OrganizationID: opt.OrganizationID,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), profileTestTimeout)
+	defer cancel()
+
+	res, err := client.ListModelsWithContext(ctx)
+	if err != nil {
+		if errors.Is(err, openai.ErrorOpenAIUnauthorized) {
+			fmt.Fprintf(os.Stderr, "%s. Please check the API key using `oax profile test`.\n", err)
+		} else {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+		}
+
+		return err
+	}
+
+	aliasesByModel := map[string][]string{}
+	for name, alias := range opt.Aliases {
+		aliasesByModel[alias.Model] = append(aliasesByModel[alias.Model], name)
+	}
+
+	var modelIDs []string
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MODEL\tOWNED BY\tALIASES")
+
+	for _, model := range res.Data {
+		modelIDs = append(modelIDs, model.ID)
+		if !oax.IsChatModel(model.ID) && !opt.All {
+			continue
+		}
+
+		aliases := aliasesByModel[model.ID]
+		sort.Strings(aliases)
+		fmt.Fprintf(w, "%s\t%s\t%s\n", model.ID, model.OwnedBy, strings.Join(aliases, ", "))
+	}
+
+	err = w.Flush()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	cache, err := oax.OpenModelCache(opt.ConfigDir)
+	if err == nil {
+		cache.Set(opt.ProfileName, modelIDs)
+		err = cache.Save()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	return nil
+}
+
+func printModelAliases(aliases map[string]oax.ModelAlias) error {
+	var names []string
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ALIAS\tPROVIDER\tPROFILE\tMODEL\tCONTEXT\tPARAMS")
+
+	for _, name := range names {
+		alias := aliases[name]
+
+		provider := alias.Provider
+		if provider == "" {
+			provider = oax.ProviderOpenAI
+		}
+
+		contextSize := ""
+		if alias.ContextSize > 0 {
+			contextSize = fmt.Sprint(alias.ContextSize)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", name, provider, alias.Profile, alias.Model, contextSize, formatModelParams(alias.Params))
+	}
+
+	err := w.Flush()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	return nil
+}
+
+func formatModelParams(params oax.ModelParams) string {
+	var items []string
+
+	if params.Temperature != nil {
+		items = append(items, fmt.Sprintf("temperature=%g", *params.Temperature))
+	}
+	if params.TopP != nil {
+		items = append(items, fmt.Sprintf("topP=%g", *params.TopP))
+	}
+	if params.MaxTokens != nil {
+		items = append(items, fmt.Sprintf("maxTokens=%d", *params.MaxTokens))
+	}
+	if params.PresencePenalty != nil {
+		items = append(items, fmt.Sprintf("presencePenalty=%g", *params.PresencePenalty))
+	}
+	if params.FrequencyPenalty != nil {
+		items = append(items, fmt.Sprintf("frequencyPenalty=%g", *params.FrequencyPenalty))
+	}
+
+	return strings.Join(items, " ")
+}
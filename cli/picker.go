@@ -0,0 +1,210 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	fuzzyfinder "github.com/ktr0731/go-fuzzyfinder"
+	"github.com/mattn/go-runewidth"
+	"github.com/shuntaka9576/oax"
+)
+
+const (
+	pickerLabelMaxWidth = 100
+	ansiReset           = "\x1b[0m"
+	ansiBold            = "\x1b[1m"
+	ansiDim             = "\x1b[2m"
+)
+
+var roleColors = map[string]string{
+	"system":    "\x1b[33m",
+	"user":      "\x1b[32m",
+	"assistant": "\x1b[36m",
+}
+
+// pickChatLogFile lets the user choose a chat log with fuzzy matching over
+// the title and the first user message, showing the conversation in a
+// preview pane. Archived logs are only listed with opt.Archived. When
+// opt.Search is set, only logs matching the full-text
+// (or, with opt.Semantic, the semantic) query are listed, best match first.
+func pickChatLogFile(opt *ChatOption) (string, error) {
+	listOption := opt.ListOption
+	listOption.IncludeArchived = opt.Archived
+
+	files, err := oax.ListFiles(opt.ChatLogDir, listOption)
+	if err != nil {
+		return "", err
+	}
+
+	index, err := oax.UpdateSearchIndex(searchIndexPath(opt.DataDir, opt.ChatLogDir), opt.ChatLogDir)
+	if err != nil {
+		if opt.Search != "" && !opt.Semantic {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+
+			return "", err
+		}
+
+		// The picker still works on file names without the index.
+		index = &oax.SearchIndex{}
+	}
+
+	if opt.Search != "" {
+		var paths []string
+
+		if opt.Semantic {
+			results, err := semanticSearchChatLogs(&SearchOption{
+				DataDir:        opt.DataDir,
+				ChatLogDir:     opt.ChatLogDir,
+				Query:          opt.Search,
+				APIKey:         opt.APIKey,
+				OrganizationID: opt.OrganizationID,
+				EmbeddingModel: opt.EmbeddingModel,
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s\n", err)
+
+				return "", err
+			}
+			for _, result := range results {
+				paths = append(paths, result.FilePath)
+			}
+		} else {
+			results, err := searchIndex(index, &SearchOption{Query: opt.Search})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s\n", err)
+
+				return "", err
+			}
+			for _, result := range results {
+				paths = append(paths, result.FilePath)
+			}
+		}
+
+		files = filterFilesByPaths(files, paths)
+		if len(files) == 0 {
+			err := fmt.Errorf("no chat logs matched %q", opt.Search)
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+
+			return "", err
+		}
+	}
+
+	labels := make([]string, len(files))
+	for i, file := range files {
+		labels[i] = pickerLabel(file, index.Entries[file.FileFullPath])
+	}
+
+	i, err := fuzzyfinder.Find(files, func(i int) string {
+		return labels[i]
+	}, fuzzyfinder.WithPreviewWindow(func(i, width, height int) string {
+		if i == -1 {
+			return ""
+		}
+
+		return pickerPreview(files[i], index.Entries[files[i].FileFullPath], width/2-5, height-2)
+	}))
+	if err != nil {
+		return "", err
+	}
+
+	return files[i].FileFullPath, nil
+}
+
+// filterFilesByPaths keeps the files in paths, in the order of paths.
+func filterFilesByPaths(files []oax.FileInfo, paths []string) []oax.FileInfo {
+	byPath := map[string]oax.FileInfo{}
+	for _, file := range files {
+		byPath[file.FileFullPath] = file
+	}
+
+	var filtered []oax.FileInfo
+	for _, path := range paths {
+		if file, ok := byPath[path]; ok {
+			filtered = append(filtered, file)
+		}
+	}
+
+	return filtered
+}
+
+// pickerItemTitle prefixes the title with the subdirectory of the chat log
+// so that project folders show up as groups.
+func pickerItemTitle(file oax.FileInfo) string {
+	if file.Group == "" {
+		return file.Title
+	}
+
+	return "[" + file.Group + "] " + file.Title
+}
+
+func pickerLabel(file oax.FileInfo, entry oax.SearchIndexEntry) string {
+	label := pickerItemTitle(file)
+
+	for _, message := range entry.Messages {
+		if message.Role == "user" && message.Content != contentUserDefault {
+			label += "  " + strings.Join(strings.Fields(message.Content), " ")
+
+			break
+		}
+	}
+
+	return runewidth.Truncate(label, pickerLabelMaxWidth, "...")
+}
+
+func pickerPreview(file oax.FileInfo, entry oax.SearchIndexEntry, width int, height int) string {
+	if width <= 0 || height <= 0 {
+		return ""
+	}
+
+	header := []string{ansiBold + runewidth.Truncate(pickerItemTitle(file), width, "...") + ansiReset}
+
+	var details []string
+	if entry.Model != "" {
+		details = append(details, entry.Model)
+	}
+	details = append(details, fmt.Sprintf("%d messages", len(entry.Messages)))
+	details = append(details, file.ModTime.Format("2006-01-02 15:04"))
+	header = append(header, ansiDim+runewidth.Truncate(strings.Join(details, " | "), width, "...")+ansiReset, "")
+
+	// Show the end of the conversation, which is where a resumed chat
+	// continues.
+	var body []string
+	for _, message := range entry.Messages {
+		color, ok := roleColors[message.Role]
+		if !ok {
+			color = "\x1b[35m"
+		}
+		body = append(body, color+ansiBold+message.Role+ansiReset)
+		body = append(body, wrapText(message.Content, width)...)
+		body = append(body, "")
+	}
+
+	if room := height - len(header); room < len(body) {
+		if room < 0 {
+			room = 0
+		}
+		body = body[len(body)-room:]
+	}
+
+	return strings.Join(append(header, body...), "\n")
+}
+
+// wrapText breaks text into lines no wider than width display cells.
+func wrapText(text string, width int) []string {
+	var lines []string
+
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\t", "    "), "\n") {
+		for runewidth.StringWidth(line) > width {
+			cut := runewidth.Truncate(line, width, "")
+			if cut == "" {
+				break
+			}
+			lines = append(lines, cut)
+			line = line[len(cut):]
+		}
+		lines = append(lines, line)
+	}
+
+	return lines
+}
@@ -0,0 +1,260 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/shuntaka9576/oax"
+	"github.com/shuntaka9576/oax/openai"
+)
+
+const profileTestTimeout = 30 * time.Second
+
+type ProfileAddOption struct {
+	Name           string
+	Description    string
+	OrganizationID string
+	APIKeyEnv      string
+	APIKeyCommand  string
+	// Store saves the API key in the encrypted key store instead of
+	// profiles.toml.
+	Store   bool
+	Default bool
+}
+
+func ProfileList(config *oax.Config) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tDEFAULT\tAPI KEY\tORGANIZATION\tDESCRIPTION")
+
+	for _, profile := range config.Profiles {
+		defaultMark := ""
+		if profile.Default {
+			defaultMark = "*"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", profile.Name, defaultMark, apiKeyLabel(profile), profile.OrganizationID, profile.Description)
+	}
+
+	err := w.Flush()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	return nil
+}
+
+func ProfileShow(config *oax.Config, name string) error {
+	var profile *oax.Profile
+	for i := range config.Profiles {
+		if config.Profiles[i].Name == name {
+			profile = &config.Profiles[i]
+		}
+	}
+
+	if profile == nil {
+		err := fmt.Errorf("invalid profile %s", name)
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "name\t%s\n", profile.Name)
+	fmt.Fprintf(w, "default\t%t\n", profile.Default)
+	fmt.Fprintf(w, "apiKey\t%s\n", apiKeyLabel(*profile))
+	fmt.Fprintf(w, "organizationId\t%s\n", profile.OrganizationID)
+	fmt.Fprintf(w, "description\t%s\n", profile.Description)
+
+	err := w.Flush()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	return nil
+}
+
+// ProfileAdd adds a profile. Unless the key is read from an environment
+// variable or a command, it is prompted for without echo.
+func ProfileAdd(config *oax.Config, opt *ProfileAddOption) error {
+	profile := oax.Profile{
+		Name:           opt.Name,
+		Description:    opt.Description,
+		OrganizationID: opt.OrganizationID,
+		ApiKeyEnv:      opt.APIKeyEnv,
+		ApiKeyCommand:  opt.APIKeyCommand,
+		Default:        opt.Default,
+	}
+
+	for _, p := range config.Profiles {
+		if p.Name == opt.Name {
+			err := fmt.Errorf("profile %s already exists", opt.Name)
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+
+			return err
+		}
+	}
+
+	if opt.APIKeyEnv == "" && opt.APIKeyCommand == "" {
+		apiKey, err := oax.ReadSecret(fmt.Sprintf("API key for profile %s: ", opt.Name))
+		if err == nil && apiKey == "" {
+			err = fmt.Errorf("empty API key")
+		}
+		if err == nil && opt.Store {
+			profile.ApiKeyStore = true
+			err = config.KeyStore().Set(opt.Name, apiKey)
+		} else {
+			profile.ApiKey = apiKey
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+
+			return err
+		}
+	}
+
+	err := oax.AddProfile(profile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	fmt.Printf("added profile %s\n", opt.Name)
+
+	return nil
+}
+
+func ProfileRemove(config *oax.Config, name string) error {
+	profile, err := oax.RemoveProfile(name)
+	if err == nil && profile.ApiKeyStore {
+		err = config.KeyStore().Delete(name)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	fmt.Printf("removed profile %s\n", name)
+	if profile.Default {
+		fmt.Println("there is no default profile now; choose one with `oax profile set-default <name>`")
+	}
+
+	return nil
+}
+
+func ProfileSetDefault(name string) error {
+	err := oax.SetDefaultProfile(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	fmt.Printf("default profile: %s\n", name)
+
+	return nil
+}
+
+// ProfileTest checks the API key of a profile, the default one when name
+// is empty, by listing models, and caches its chat models.
+func ProfileTest(config *oax.Config, name string) error {
+	if name != "" {
+		config.ProfileName = name
+	}
+
+	profile, err := config.SelectProfile()
+	if err == nil {
+		err = config.ResolveAPIKey(&profile)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	client := openai.InitClient(&openai.InitClientOptions{
+		APIKey:         profile.ApiKey,
+		OrganizationID: profile.OrganizationID,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), profileTestTimeout)
+	defer cancel()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "profile\t%s\n", profile.Name)
+
+	res, err := client.ListModelsWithContext(ctx)
+	if err != nil {
+		if errors.Is(err, openai.ErrorOpenAIUnauthorized) {
+			fmt.Fprintf(w, "auth\tfailed: invalid API key or organization\n")
+		} else {
+			fmt.Fprintf(w, "auth\tunknown: %s\n", err)
+		}
+		w.Flush()
+
+		return err
+	}
+
+	var modelIDs, chatModels []string
+	for _, model := range res.Data {
+		modelIDs = append(modelIDs, model.ID)
+		if oax.IsChatModel(model.ID) {
+			chatModels = append(chatModels, model.ID)
+		}
+	}
+
+	organization := res.Organization
+	if organization == "" {
+		organization = profile.OrganizationID
+	}
+
+	fmt.Fprintf(w, "auth\tok\n")
+	fmt.Fprintf(w, "organization\t%s\n", organization)
+	fmt.Fprintf(w, "chat models\t%s\n", strings.Join(chatModels, ", "))
+
+	err = w.Flush()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	cache, err := oax.OpenModelCache(config.ConfigDir)
+	if err == nil {
+		cache.Set(profile.Name, modelIDs)
+		err = cache.Save()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	return nil
+}
+
+// apiKeyLabel describes the API key of a profile without revealing it.
+func apiKeyLabel(profile oax.Profile) string {
+	switch {
+	case profile.ApiKey != "":
+		return oax.MaskAPIKey(profile.ApiKey)
+	case profile.ApiKeyEnv != "":
+		return "env " + profile.ApiKeyEnv
+	case profile.ApiKeyCommand != "":
+		return "command " + profile.ApiKeyCommand
+	case profile.ApiKeyStore:
+		return "key store"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,223 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/shuntaka9576/oax"
+	"github.com/shuntaka9576/oax/openai"
+)
+
+type SearchOption struct {
+	DataDir        string
+	ChatLogDir     string
+	Query          string
+	Regex          bool
+	Semantic       bool
+	Roles          []string
+	Since          string
+	Until          string
+	Limit          int
+	APIKey         string
+	OrganizationID string
+	EmbeddingModel string
+}
+
+func Search(opt *SearchOption) error {
+	if opt.Semantic {
+		return semanticSearch(opt)
+	}
+
+	results, err := searchChatLogs(opt)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	if opt.Limit > 0 && len(results) > opt.Limit {
+		results = results[:opt.Limit]
+	}
+
+	for _, result := range results {
+		chatLog := oax.ChatLog{FilePath: &result.FilePath}
+		filePathForUser, err := chatLog.FilePathForUser()
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s (%s)\n", filePathForUser, result.ModTime.Format("2006-01-02 15:04"))
+		for _, match := range result.Matches {
+			fmt.Printf("  %s: %s\n", match.Role, match.Snippet)
+		}
+	}
+
+	if len(results) == 0 {
+		fmt.Fprintf(os.Stderr, "no chat logs matched.\n")
+	}
+
+	return nil
+}
+
+func semanticSearch(opt *SearchOption) error {
+	if opt.Regex {
+		err := fmt.Errorf("--regex cannot be combined with --semantic")
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	results, err := semanticSearchChatLogs(opt)
+	if err != nil {
+		if errors.Is(err, openai.ErrorOpenAIUnauthorized) {
+			fmt.Fprintf(os.Stderr, "%s. Please check if the API key is correct using `oax config --profiles`.\n", err)
+		} else {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+		}
+
+		return err
+	}
+
+	for _, result := range results {
+		chatLog := oax.ChatLog{FilePath: &result.FilePath}
+		filePathForUser, err := chatLog.FilePathForUser()
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s (%.3f, %s)\n", filePathForUser, result.Score, result.ModTime.Format("2006-01-02 15:04"))
+		fmt.Printf("  %s: %s\n", result.Match.Role, result.Match.Snippet)
+	}
+
+	if len(results) == 0 {
+		fmt.Fprintf(os.Stderr, "no chat logs matched.\n")
+	}
+
+	return nil
+}
+
+// semanticSearchChatLogs embeds the chat logs that changed since the last
+// search and ranks all conversations by similarity to the query.
+func semanticSearchChatLogs(opt *SearchOption) ([]oax.SemanticResult, error) {
+	query := oax.SemanticQuery{
+		Text:  opt.Query,
+		Roles: opt.Roles,
+		Limit: opt.Limit,
+	}
+
+	var err error
+	if query.Since, query.Until, err = parseDateRange(opt.Since, opt.Until); err != nil {
+		return nil, err
+	}
+
+	files, err := oax.ListFiles(opt.ChatLogDir, oax.ListOption{Recursive: true, IncludeArchived: true})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	index := newVectorIndex(opt.DataDir, opt.ChatLogDir, opt.APIKey, opt.OrganizationID, opt.EmbeddingModel)
+	if err := index.Update(ctx, files); err != nil {
+		return nil, err
+	}
+
+	return index.Search(ctx, query)
+}
+
+type openAIEmbedder struct {
+	client *openai.Client
+	model  string
+}
+
+func (e openAIEmbedder) Embed(ctx context.Context, inputs []string) ([][]float32, error) {
+	res, err := e.client.CreateEmbeddingsWithContext(ctx, &openai.CreateEmbeddingsOption{
+		Model: e.model,
+		Input: inputs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var vectors [][]float32
+	for _, data := range res.Data {
+		vectors = append(vectors, data.Embedding)
+	}
+
+	return vectors, nil
+}
+
+func searchIndexPath(dataDir string, chatLogDir string) string {
+	return filepath.Join(oax.IndexDir(dataDir, chatLogDir), oax.SearchIndexFileName)
+}
+
+func newVectorIndex(dataDir string, chatLogDir string, apiKey string, organizationID string, model string) *oax.VectorIndex {
+	if model == "" {
+		model = openai.DefaultEmbeddingModel
+	}
+
+	client := openai.InitClient(&openai.InitClientOptions{
+		APIKey:         apiKey,
+		OrganizationID: organizationID,
+	})
+
+	index := oax.NewVectorIndex(filepath.Join(oax.IndexDir(dataDir, chatLogDir), oax.VectorIndexDirName), openAIEmbedder{client: client, model: model}, model)
+	index.Skip = func(message oax.ChatMessage) bool {
+		return message.Content == contentUserDefault
+	}
+
+	return index
+}
+
+func searchChatLogs(opt *SearchOption) ([]oax.SearchResult, error) {
+	index, err := oax.UpdateSearchIndex(searchIndexPath(opt.DataDir, opt.ChatLogDir), opt.ChatLogDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return searchIndex(index, opt)
+}
+
+func searchIndex(index *oax.SearchIndex, opt *SearchOption) ([]oax.SearchResult, error) {
+	query := oax.SearchQuery{
+		Pattern: opt.Query,
+		Regex:   opt.Regex,
+		Roles:   opt.Roles,
+	}
+
+	var err error
+	if query.Since, query.Until, err = parseDateRange(opt.Since, opt.Until); err != nil {
+		return nil, err
+	}
+
+	return index.Search(query)
+}
+
+func parseDateRange(since string, until string) (sinceTime time.Time, untilTime time.Time, err error) {
+	if since != "" {
+		if sinceTime, err = parseDate(since); err != nil {
+			return
+		}
+	}
+	if until != "" {
+		if untilTime, err = parseDate(until); err != nil {
+			return
+		}
+		// --until is inclusive of the whole day.
+		untilTime = untilTime.AddDate(0, 0, 1)
+	}
+
+	return
+}
+
+func parseDate(value string) (time.Time, error) {
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", value)
+	}
+
+	return t, nil
+}
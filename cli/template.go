@@ -0,0 +1,211 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	fuzzyfinder "github.com/ktr0731/go-fuzzyfinder"
+	"github.com/shuntaka9576/oax"
+	"golang.org/x/term"
+)
+
+// TemplateList prints the templates of the settings and the template
+// directories with the file defining each.
+func TemplateList(config *oax.Config) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tDESCRIPTION\tFILE")
+
+	for _, template := range config.Settings.Chat.Templates {
+		name := template.Name
+		if name == config.Settings.Chat.DefaultTemplate {
+			name += " *"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, template.Description, template.FilePath)
+	}
+
+	err := w.Flush()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	return nil
+}
+
+// TemplateShow prints a template with its variables and messages.
+func TemplateShow(config *oax.Config, name string) error {
+	template, err := oax.FindTemplate(config.Settings.Chat.Templates, name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	fmt.Print(formatTemplate(*template))
+
+	return nil
+}
+
+// TemplateNew creates a template file in the global template directory,
+// or with project in the one of the project, and opens it in the editor.
+func TemplateNew(config *oax.Config, name string, project bool) error {
+	if template, _ := oax.FindTemplate(config.Settings.Chat.Templates, name); template != nil {
+		err := fmt.Errorf("template %s already exists. Use oax template edit %s", name, name)
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	dir, err := config.TemplateDir(project)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	filePath, err := oax.NewTemplateFile(dir, name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+	fmt.Printf("Created %s\n", filePath)
+
+	return oax.InitEditor(config.Settings.Setting.Editor).Open(filePath)
+}
+
+// TemplateEdit opens the file defining a template, which is settings.toml
+// for the templates written there, in the editor. A template that cannot be
+// resolved, or a template file that cannot be loaded and is found by its
+// file name, is opened too so that it can be fixed.
+func TemplateEdit(config *oax.Config, name string) error {
+	template, err := oax.FindTemplate(config.Settings.Chat.Templates, name)
+	if template == nil {
+		if filePath := config.FindTemplateFile(name); filePath != "" {
+			return oax.InitEditor(config.Settings.Setting.Editor).Open(filePath)
+		}
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	return oax.InitEditor(config.Settings.Setting.Editor).Open(template.FilePath)
+}
+
+// PickTemplate lets the user choose a template with fuzzy matching over
+// the name and description, showing the template in a preview pane.
+func PickTemplate(templates []oax.ChatTemplate) (*oax.ChatTemplate, error) {
+	if len(templates) == 0 {
+		err := fmt.Errorf("no templates. Create one with oax template new <name>")
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return nil, err
+	}
+
+	i, err := fuzzyfinder.Find(templates, func(i int) string {
+		label := templates[i].Name
+		if templates[i].Description != "" {
+			label += "  " + templates[i].Description
+		}
+
+		return label
+	}, fuzzyfinder.WithPreviewWindow(func(i, width, height int) string {
+		if i == -1 {
+			return ""
+		}
+
+		var lines []string
+		for _, line := range strings.Split(formatTemplate(templates[i]), "\n") {
+			lines = append(lines, wrapText(line, width/2-5)...)
+		}
+		if len(lines) > height-2 && height > 2 {
+			lines = lines[:height-2]
+		}
+
+		return strings.Join(lines, "\n")
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := oax.FindTemplate(templates, templates[i].Name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return nil, err
+	}
+
+	return template, nil
+}
+
+func formatTemplate(template oax.ChatTemplate) string {
+	var builder strings.Builder
+
+	builder.WriteString(fmt.Sprintf("# %s\n", template.Name))
+	if template.Description != "" {
+		builder.WriteString(fmt.Sprintf("%s\n", template.Description))
+	}
+	if template.FilePath != "" {
+		builder.WriteString(fmt.Sprintf("file: %s\n", template.FilePath))
+	}
+	if template.Extends != "" {
+		builder.WriteString(fmt.Sprintf("extends: %s\n", template.Extends))
+	}
+	if len(template.Include) > 0 {
+		builder.WriteString(fmt.Sprintf("include: %s\n", strings.Join(template.Include, ", ")))
+	}
+
+	if len(template.Params) > 0 {
+		builder.WriteString("\nVariables:\n")
+		for _, param := range template.Params {
+			line := "  " + param.Name
+			if param.Description != "" {
+				line += " - " + param.Description
+			}
+			if param.Default != "" {
+				line += fmt.Sprintf(" (default %q)", param.Default)
+			}
+			builder.WriteString(line + "\n")
+		}
+	}
+
+	for _, message := range template.Messages {
+		builder.WriteString(fmt.Sprintf("\n## %s\n%s\n", message.Role, message.Content))
+	}
+
+	return builder.String()
+}
+
+// promptTemplateParam asks for the value of a template variable on the
+// terminal. The default, if any, is used for an empty answer.
+func promptTemplateParam(param oax.TemplateParam) (string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("no value for template variable %s. Use --var %s=value", param.Name, param.Name)
+	}
+
+	prompt := param.Name
+	if param.Description != "" {
+		prompt += fmt.Sprintf(" (%s)", param.Description)
+	}
+	if param.Default != "" {
+		prompt += fmt.Sprintf(" [%s]", param.Default)
+	}
+	fmt.Printf("%s: ", prompt)
+
+	reader := bufio.NewReader(os.Stdin)
+	value, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	value = strings.TrimRight(value, "\r\n")
+	if value == "" {
+		value = param.Default
+	}
+
+	return value, nil
+}
@@ -0,0 +1,722 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+	"github.com/shuntaka9576/oax"
+	"github.com/shuntaka9576/oax/openai"
+	"golang.org/x/term"
+)
+
+const (
+	tuiInputMaxRows = 8
+	tuiTabWidth     = 4
+	tuiKeyHints     = "Ctrl+S send  Ctrl+R regenerate  Ctrl+E edit last  Ctrl+B branch  Ctrl+O model  PgUp/PgDn scroll  Esc cancel  Ctrl+C quit"
+)
+
+var tuiRoleColors = map[string]tcell.Color{
+	"system":    tcell.ColorOlive,
+	"user":      tcell.ColorGreen,
+	"assistant": tcell.ColorTeal,
+}
+
+type tuiLine struct {
+	text  string
+	style tcell.Style
+}
+
+// tuiInputPosition is the cell of the input box a cursor index is drawn at.
+type tuiInputPosition struct {
+	x, y int
+}
+
+// tuiStream is the answer being streamed. The request goroutine appends
+// to it and wakes up the event loop, which may drop wake-ups when its
+// queue is full, so the state is shared rather than sent in the events.
+type tuiStream struct {
+	mu      sync.Mutex
+	content strings.Builder
+	done    bool
+	err     error
+}
+
+type chatTUI struct {
+	screen  tcell.Screen
+	opt     *ChatOption
+	chatLog *oax.ChatLog
+	client  *openai.Client
+
+	input  []rune
+	cursor int
+	// editing is set while the input holds a message moved back by
+	// editLast. The messages from editFrom on are hidden but stay in the
+	// chat log until the input is sent.
+	editing  bool
+	editFrom int
+	// scroll is the number of transcript lines hidden below the view. Zero
+	// follows the end of the conversation.
+	scroll int
+	status string
+
+	stream *tuiStream
+	cancel context.CancelFunc
+
+	modelIndex int
+}
+
+// runChatTUI runs the conversation of chatLog in a full-screen terminal UI
+// until the user quits. Messages are written to the chat log as they are
+// sent and answered, as in the editor flow.
+func runChatTUI(chatLog *oax.ChatLog, opt *ChatOption) error {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		err := fmt.Errorf("--tui needs a terminal")
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	screen, err := tcell.NewScreen()
+	if err == nil {
+		err = screen.Init()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		return err
+	}
+
+	t := &chatTUI{
+		screen:  screen,
+		opt:     opt,
+		chatLog: chatLog,
+		client: openai.InitClient(&openai.InitClientOptions{
+			APIKey:         opt.APIKey,
+			OrganizationID: opt.OrganizationID,
+		}),
+		modelIndex: -1,
+	}
+	for i, choice := range opt.ModelChoices {
+		if choice.Model == opt.Model {
+			t.modelIndex = i
+
+			break
+		}
+	}
+	t.takeUnsentMessage()
+
+	t.run()
+	screen.Fini()
+
+	return t.saveInput()
+}
+
+// finishChatTUI removes a new chat log nothing was sent in and reports
+// where the conversation was saved.
+func finishChatTUI(chatLog oax.ChatLog, opt *ChatOption) error {
+	if len(chatLog.ChatLogToml.Messages) == 0 && opt.File == nil {
+		return deleteFile(chatLog)
+	}
+
+	filePathForUser, err := chatLog.FilePathForUser()
+	if err != nil {
+		return err
+	}
+
+	if opt.File == nil {
+		fmt.Fprintf(os.Stderr, "saved: %s\n", filePathForUser)
+	} else {
+		fmt.Fprintf(os.Stderr, "updated: %s\n", filePathForUser)
+	}
+
+	return nil
+}
+
+// takeUnsentMessage moves a trailing user message, such as the turn of a
+// template or a message whose request failed, into the input box so that
+// it can be edited before it is sent. The comment of the editor flow is
+// dropped.
+func (t *chatTUI) takeUnsentMessage() {
+	messages := t.chatLog.ChatLogToml.Messages
+	if len(messages) == 0 {
+		return
+	}
+
+	last := messages[len(messages)-1]
+	if isLastEmptyMessage(messages) {
+		t.chatLog.ChatLogToml.Messages = messages[:len(messages)-1]
+	} else if last.Role == "user" {
+		t.chatLog.ChatLogToml.Messages = messages[:len(messages)-1]
+		t.input = []rune(last.Content)
+		t.cursor = len(t.input)
+	}
+}
+
+// saveInput keeps the text left in the input box as the last message, so
+// that resuming the chat brings it back. An edit that was not sent is
+// dropped so that the messages it would replace are kept.
+func (t *chatTUI) saveInput() error {
+	if content := strings.TrimSpace(string(t.input)); content != "" && !t.editing {
+		t.chatLog.AddChatMessage(oax.ChatMessage{Role: t.opt.Role, Content: content})
+	}
+
+	return t.chatLog.FlushFile()
+}
+
+func (t *chatTUI) run() {
+	for {
+		t.draw()
+
+		switch ev := t.screen.PollEvent().(type) {
+		case *tcell.EventResize:
+			t.screen.Sync()
+		case *tcell.EventInterrupt:
+			t.receive()
+		case *tcell.EventKey:
+			if quit := t.handleKey(ev); quit {
+				if t.cancel != nil {
+					t.cancel()
+				}
+
+				return
+			}
+		}
+	}
+}
+
+func (t *chatTUI) handleKey(ev *tcell.EventKey) bool {
+	switch ev.Key() {
+	case tcell.KeyCtrlC:
+		return true
+	case tcell.KeyCtrlD:
+		if len(t.input) == 0 && t.stream == nil {
+			return true
+		}
+		t.deleteRune(t.cursor)
+	case tcell.KeyEscape:
+		if t.cancel != nil {
+			t.cancel()
+		} else if t.editing {
+			t.cancelEdit()
+		}
+	case tcell.KeyCtrlS:
+		t.whenIdle(t.send)
+	case tcell.KeyCtrlR:
+		t.whenIdle(t.regenerate)
+	case tcell.KeyCtrlE:
+		t.whenIdle(t.editLast)
+	case tcell.KeyCtrlB:
+		t.whenIdle(t.branch)
+	case tcell.KeyCtrlO:
+		t.whenIdle(t.switchModel)
+	case tcell.KeyPgUp:
+		t.scroll += t.pageSize()
+	case tcell.KeyPgDn:
+		t.scroll -= t.pageSize()
+		if t.scroll < 0 {
+			t.scroll = 0
+		}
+	case tcell.KeyEnter:
+		t.insert('\n')
+	case tcell.KeyTab:
+		t.insert('\t')
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if t.cursor > 0 {
+			t.cursor--
+			t.deleteRune(t.cursor)
+		}
+	case tcell.KeyDelete:
+		t.deleteRune(t.cursor)
+	case tcell.KeyLeft:
+		if t.cursor > 0 {
+			t.cursor--
+		}
+	case tcell.KeyRight:
+		if t.cursor < len(t.input) {
+			t.cursor++
+		}
+	case tcell.KeyUp:
+		t.moveCursorRow(-1)
+	case tcell.KeyDown:
+		t.moveCursorRow(1)
+	case tcell.KeyHome:
+		t.moveCursorInRow(false)
+	case tcell.KeyEnd:
+		t.moveCursorInRow(true)
+	case tcell.KeyRune:
+		t.insert(ev.Rune())
+	}
+
+	return false
+}
+
+func (t *chatTUI) whenIdle(action func()) {
+	if t.stream != nil {
+		t.status = "Waiting for the answer. Esc cancels it."
+
+		return
+	}
+
+	t.status = ""
+	action()
+}
+
+func (t *chatTUI) send() {
+	content := strings.TrimSpace(string(t.input))
+	if content == "" {
+		t.status = "Type a message first."
+
+		return
+	}
+
+	messages := t.chatLog.ChatLogToml.Messages
+	if t.editing {
+		t.chatLog.ChatLogToml.Messages = messages[:t.editFrom:t.editFrom]
+	}
+	t.chatLog.AddChatMessage(oax.ChatMessage{Role: t.opt.Role, Content: content})
+	if err := t.chatLog.FlushFile(); err != nil {
+		t.chatLog.ChatLogToml.Messages = messages
+		t.status = err.Error()
+
+		return
+	}
+
+	t.input = nil
+	t.cursor = 0
+	t.editing = false
+	t.request()
+}
+
+// regenerate replaces the last answer with a new one.
+func (t *chatTUI) regenerate() {
+	if t.editing {
+		t.status = "Send the edited message, or Esc to cancel the edit."
+
+		return
+	}
+
+	messages := t.chatLog.ChatLogToml.Messages
+	if len(messages) > 0 && messages[len(messages)-1].Role == "assistant" {
+		messages = messages[:len(messages)-1]
+	}
+	if len(messages) == 0 || messages[len(messages)-1].Role != "user" {
+		t.status = "Nothing to regenerate."
+
+		return
+	}
+
+	t.chatLog.ChatLogToml.Messages = messages
+	if err := t.chatLog.FlushFile(); err != nil {
+		t.status = err.Error()
+
+		return
+	}
+
+	t.request()
+}
+
+// editLast moves the last user message back into the input box and hides
+// the messages after it. They are only dropped from the chat log when the
+// edited message is sent, so quitting or Esc keeps them, and branching first
+// keeps the original conversation. Editing again moves to the user message
+// before.
+func (t *chatTUI) editLast() {
+	if len(t.input) > 0 {
+		t.status = "Clear the input to edit the last message."
+
+		return
+	}
+
+	messages := t.visibleMessages()
+	i := len(messages) - 1
+	for i >= 0 && messages[i].Role != "user" {
+		i--
+	}
+	if i < 0 {
+		t.status = "No message to edit."
+
+		return
+	}
+
+	t.input = []rune(messages[i].Content)
+	t.cursor = len(t.input)
+	t.editing = true
+	t.editFrom = i
+
+	t.status = "Editing the last message. Ctrl+S sends it, Esc cancels."
+}
+
+// cancelEdit clears the input and shows the messages hidden by editLast.
+func (t *chatTUI) cancelEdit() {
+	t.input = nil
+	t.cursor = 0
+	t.editing = false
+
+	t.status = "Edit canceled."
+}
+
+// visibleMessages returns the messages of the chat log without those that
+// an edit replaces.
+func (t *chatTUI) visibleMessages() []oax.ChatMessage {
+	messages := t.chatLog.ChatLogToml.Messages
+	if t.editing {
+		return messages[:t.editFrom]
+	}
+
+	return messages
+}
+
+// branch continues the conversation in a copy of the chat log, leaving the
+// current one as it is.
+func (t *chatTUI) branch() {
+	branch, err := oax.BranchChatLog(t.chatLog)
+	if err != nil {
+		t.status = err.Error()
+
+		return
+	}
+	if err := branch.Lock(); err != nil {
+		t.status = err.Error()
+
+		return
+	}
+	if err := t.chatLog.Unlock(); err != nil {
+		t.status = err.Error()
+	}
+
+	*t.chatLog = *branch
+	t.status = fmt.Sprintf("Branched to %s.", t.chatLog.Title())
+}
+
+func (t *chatTUI) switchModel() {
+	choices := t.opt.ModelChoices
+	if len(choices) == 0 {
+		t.status = "No models to switch to. Add [models.<alias>] to the settings or run oax models."
+
+		return
+	}
+
+	t.modelIndex = (t.modelIndex + 1) % len(choices)
+	choice := choices[t.modelIndex]
+	t.opt.Model = choice.Model
+	t.opt.Params = choice.Params
+	t.opt.ContextSize = choice.ContextSize
+
+	t.status = fmt.Sprintf("Model: %s", modelLabel(choice))
+}
+
+func modelLabel(model oax.ResolvedModel) string {
+	if model.Alias == "" {
+		return model.Model
+	}
+
+	return fmt.Sprintf("%s (%s)", model.Alias, model.Model)
+}
+
+// request streams the answer to the conversation in the background.
+func (t *chatTUI) request() {
+	t.scroll = 0
+	t.status = contextSizeWarning(t.chatLog, t.opt)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &tuiStream{}
+	t.stream = stream
+	t.cancel = cancel
+
+	option := &openai.ChatCreateCompletionOption{
+		Messages: t.chatLog.CreateOpenAIMessages(),
+		Model:    t.opt.Model,
+		Params:   chatParams(t.opt.Params),
+	}
+
+	go func() {
+		err := t.client.ChatCreateCompletionSubscribeWithContext(ctx, option, func(event *openai.ChatCompletionResponse, err error) error {
+			if err != nil {
+				if err == io.EOF {
+					return nil
+				}
+
+				return err
+			}
+
+			if len(event.Choices) > 0 && event.Choices[0].Delta.Content != "" {
+				stream.mu.Lock()
+				stream.content.WriteString(event.Choices[0].Delta.Content)
+				stream.mu.Unlock()
+				_ = t.screen.PostEvent(tcell.NewEventInterrupt(nil))
+			}
+
+			return nil
+		})
+
+		stream.mu.Lock()
+		stream.done = true
+		stream.err = err
+		stream.mu.Unlock()
+		_ = t.screen.PostEvent(tcell.NewEventInterrupt(nil))
+	}()
+}
+
+// receive saves the answer once the stream has ended. A canceled answer
+// is kept as far as it was received.
+func (t *chatTUI) receive() {
+	stream := t.stream
+	if stream == nil {
+		return
+	}
+
+	stream.mu.Lock()
+	done, err, content := stream.done, stream.err, stream.content.String()
+	stream.mu.Unlock()
+	if !done {
+		return
+	}
+
+	t.cancel()
+	t.stream = nil
+	t.cancel = nil
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		t.status = "Canceled."
+	case errors.Is(err, openai.ErrorOpenAIUnauthorized):
+		t.status = fmt.Sprintf("%s. Please check the API key using `oax profile test`.", err)
+	case err != nil:
+		t.status = err.Error()
+	}
+
+	if content == "" {
+		return
+	}
+
+	t.chatLog.ChatLogToml.Model = t.opt.Model
+	t.chatLog.AddChatMessage(oax.ChatMessage{Role: "assistant", Content: content})
+	if err := t.chatLog.FlushFile(); err != nil {
+		t.status = err.Error()
+	}
+}
+
+func (t *chatTUI) insert(r rune) {
+	t.input = append(t.input, 0)
+	copy(t.input[t.cursor+1:], t.input[t.cursor:])
+	t.input[t.cursor] = r
+	t.cursor++
+}
+
+func (t *chatTUI) deleteRune(i int) {
+	if i < 0 || i >= len(t.input) {
+		return
+	}
+
+	t.input = append(t.input[:i], t.input[i+1:]...)
+}
+
+func (t *chatTUI) moveCursorRow(dy int) {
+	width, _ := t.screen.Size()
+	_, positions := layoutInput(t.input, width)
+
+	current := positions[t.cursor]
+	target := current.y + dy
+	if target < 0 {
+		t.cursor = 0
+
+		return
+	}
+	if target > positions[len(positions)-1].y {
+		t.cursor = len(t.input)
+
+		return
+	}
+
+	// The rightmost index of the target row that is not right of the
+	// cursor keeps the column as far as the row allows.
+	cursor := -1
+	for i, position := range positions {
+		if position.y == target && (cursor == -1 || position.x <= current.x) {
+			cursor = i
+		}
+	}
+	t.cursor = cursor
+}
+
+func (t *chatTUI) moveCursorInRow(end bool) {
+	width, _ := t.screen.Size()
+	_, positions := layoutInput(t.input, width)
+
+	row := positions[t.cursor].y
+	for i, position := range positions {
+		if position.y != row {
+			continue
+		}
+		if !end {
+			t.cursor = i
+
+			return
+		}
+		t.cursor = i
+	}
+}
+
+func (t *chatTUI) pageSize() int {
+	_, height := t.screen.Size()
+	if height < 8 {
+		return 1
+	}
+
+	return height / 2
+}
+
+func (t *chatTUI) draw() {
+	t.screen.Clear()
+	width, height := t.screen.Size()
+
+	rows, positions := layoutInput(t.input, width)
+	cursor := positions[t.cursor]
+
+	inputHeight := len(rows)
+	if inputHeight > tuiInputMaxRows {
+		inputHeight = tuiInputMaxRows
+	}
+	inputTop := height - inputHeight
+	firstRow := 0
+	if cursor.y >= inputHeight {
+		firstRow = cursor.y - inputHeight + 1
+	}
+
+	header := fmt.Sprintf(" oax  %s  %s", t.chatLog.Title(), t.opt.Model)
+	if t.stream != nil {
+		header += "  answering..."
+	}
+	t.drawLine(0, header, tcell.StyleDefault.Reverse(true), width, true)
+
+	transcriptHeight := inputTop - 2
+	lines := t.transcriptLines(width)
+	maxScroll := len(lines) - transcriptHeight
+	if maxScroll < 0 {
+		maxScroll = 0
+	}
+	if t.scroll > maxScroll {
+		t.scroll = maxScroll
+	}
+	start := len(lines) - transcriptHeight - t.scroll
+	if start < 0 {
+		start = 0
+	}
+	for y := 0; y < transcriptHeight && start+y < len(lines); y++ {
+		t.drawLine(1+y, lines[start+y].text, lines[start+y].style, width, false)
+	}
+
+	status := t.status
+	if status == "" {
+		status = tuiKeyHints
+	}
+	if t.scroll > 0 {
+		status = fmt.Sprintf("[%d more lines below] %s", t.scroll, status)
+	}
+	t.drawLine(inputTop-1, status, tcell.StyleDefault.Dim(true).Underline(true), width, true)
+
+	for y := 0; y < inputHeight && firstRow+y < len(rows); y++ {
+		t.drawLine(inputTop+y, rows[firstRow+y], tcell.StyleDefault, width, false)
+	}
+
+	t.screen.ShowCursor(cursor.x, inputTop+cursor.y-firstRow)
+	t.screen.Show()
+}
+
+// transcriptLines lays out the conversation and the answer being streamed.
+func (t *chatTUI) transcriptLines(width int) []tuiLine {
+	var lines []tuiLine
+
+	appendMessage := func(role string, content string) {
+		color, ok := tuiRoleColors[role]
+		if !ok {
+			color = tcell.ColorPurple
+		}
+		lines = append(lines, tuiLine{role, tcell.StyleDefault.Foreground(color).Bold(true)})
+		for _, line := range wrapText(content, width) {
+			lines = append(lines, tuiLine{line, tcell.StyleDefault})
+		}
+		lines = append(lines, tuiLine{"", tcell.StyleDefault})
+	}
+
+	for _, message := range t.visibleMessages() {
+		appendMessage(message.Role, message.Content)
+	}
+
+	if t.stream != nil {
+		t.stream.mu.Lock()
+		content := t.stream.content.String()
+		t.stream.mu.Unlock()
+		appendMessage("assistant", content+"▍")
+	}
+
+	return lines
+}
+
+// drawLine draws text on row y, filling the rest of the row with the style
+// when fill is set.
+func (t *chatTUI) drawLine(y int, text string, style tcell.Style, width int, fill bool) {
+	x := 0
+	for _, r := range runewidth.Truncate(text, width, "") {
+		w := runewidth.RuneWidth(r)
+		if w == 0 {
+			continue
+		}
+		t.screen.SetContent(x, y, r, nil, style)
+		x += w
+	}
+
+	for ; fill && x < width; x++ {
+		t.screen.SetContent(x, y, ' ', nil, style)
+	}
+}
+
+// layoutInput wraps the input to width and returns its rows and where the
+// cursor is drawn for each index of input, including the end.
+func layoutInput(input []rune, width int) ([]string, []tuiInputPosition) {
+	if width < 1 {
+		width = 1
+	}
+
+	rows := []string{""}
+	positions := make([]tuiInputPosition, 0, len(input)+1)
+	x := 0
+
+	for _, r := range input {
+		text := string(r)
+		if r == '\t' {
+			text = strings.Repeat(" ", tuiTabWidth)
+		}
+		w := runewidth.StringWidth(text)
+
+		if r != '\n' && x > 0 && x+w > width {
+			rows = append(rows, "")
+			x = 0
+		}
+		positions = append(positions, tuiInputPosition{x, len(rows) - 1})
+
+		if r == '\n' {
+			rows = append(rows, "")
+			x = 0
+
+			continue
+		}
+		rows[len(rows)-1] += text
+		x += w
+	}
+
+	if x >= width {
+		rows = append(rows, "")
+		x = 0
+	}
+	positions = append(positions, tuiInputPosition{x, len(rows) - 1})
+
+	return rows, positions
+}
@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shuntaka9576/oax"
+)
+
+func TestLayoutInput(t *testing.T) {
+	testCases := []struct {
+		name              string
+		input             string
+		width             int
+		expectedRows      []string
+		expectedPositions []tuiInputPosition
+	}{
+		{
+			"empty",
+			"",
+			10,
+			[]string{""},
+			[]tuiInputPosition{{0, 0}},
+		},
+		{
+			"newline",
+			"ab\nc",
+			10,
+			[]string{"ab", "c"},
+			[]tuiInputPosition{{0, 0}, {1, 0}, {2, 0}, {0, 1}, {1, 1}},
+		},
+		{
+			"wrap",
+			"abcde",
+			2,
+			[]string{"ab", "cd", "e"},
+			[]tuiInputPosition{{0, 0}, {1, 0}, {0, 1}, {1, 1}, {0, 2}, {1, 2}},
+		},
+		{
+			"cursor after a full row",
+			"ab",
+			2,
+			[]string{"ab", ""},
+			[]tuiInputPosition{{0, 0}, {1, 0}, {0, 1}},
+		},
+		{
+			"tab",
+			"\ta",
+			10,
+			[]string{"    a"},
+			[]tuiInputPosition{{0, 0}, {4, 0}, {5, 0}},
+		},
+		{
+			"wide rune",
+			"aあ",
+			2,
+			[]string{"a", "あ", ""},
+			[]tuiInputPosition{{0, 0}, {0, 1}, {0, 2}},
+		},
+		{
+			"zero width",
+			"ab",
+			0,
+			[]string{"a", "b", ""},
+			[]tuiInputPosition{{0, 0}, {0, 1}, {0, 2}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rows, positions := layoutInput([]rune(tc.input), tc.width)
+			if !reflect.DeepEqual(rows, tc.expectedRows) {
+				t.Errorf("Expected %q but got %q", tc.expectedRows, rows)
+			}
+			if !reflect.DeepEqual(positions, tc.expectedPositions) {
+				t.Errorf("Expected %v but got %v", tc.expectedPositions, positions)
+			}
+		})
+	}
+}
+
+func TestTakeUnsentMessage(t *testing.T) {
+	testCases := []struct {
+		name             string
+		messages         []oax.ChatMessage
+		expectedInput    string
+		expectedMessages int
+	}{
+		{
+			"no messages",
+			nil,
+			"",
+			0,
+		},
+		{
+			"answered",
+			[]oax.ChatMessage{{Role: "user", Content: "Hi"}, {Role: "assistant", Content: "Hello"}},
+			"",
+			2,
+		},
+		{
+			"unsent user message",
+			[]oax.ChatMessage{{Role: "system", Content: "Be brief."}, {Role: "user", Content: "Hi"}},
+			"Hi",
+			1,
+		},
+		{
+			"editor comment",
+			[]oax.ChatMessage{{Role: "user", Content: "Hi"}, {Role: "assistant", Content: "Hello"}, {Role: "user", Content: contentUserDefault}},
+			"",
+			2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			chatLog := &oax.ChatLog{}
+			chatLog.ChatLogToml.Messages = tc.messages
+			tui := &chatTUI{chatLog: chatLog}
+
+			tui.takeUnsentMessage()
+			if string(tui.input) != tc.expectedInput {
+				t.Errorf("Expected %q but got %q", tc.expectedInput, string(tui.input))
+			}
+			if tui.cursor != len(tui.input) {
+				t.Errorf("Expected the cursor at %d but got %d", len(tui.input), tui.cursor)
+			}
+			if len(chatLog.ChatLogToml.Messages) != tc.expectedMessages {
+				t.Errorf("Expected %d messages but got %+v", tc.expectedMessages, chatLog.ChatLogToml.Messages)
+			}
+		})
+	}
+}
+
+func TestEditLast(t *testing.T) {
+	messages := []oax.ChatMessage{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: "First"},
+		{Role: "assistant", Content: "Answer 1"},
+		{Role: "user", Content: "Second"},
+		{Role: "assistant", Content: "Answer 2"},
+	}
+
+	filePath := filepath.Join(t.TempDir(), "chat.toml")
+	chatLog := &oax.ChatLog{FilePath: &filePath}
+	chatLog.ChatLogToml.Messages = append([]oax.ChatMessage(nil), messages...)
+	tui := &chatTUI{chatLog: chatLog, opt: &ChatOption{Role: "user"}}
+
+	tui.editLast()
+	if string(tui.input) != "Second" || tui.cursor != len("Second") {
+		t.Errorf("Expected %q with the cursor at the end but got %q, %d", "Second", string(tui.input), tui.cursor)
+	}
+	if visible := tui.visibleMessages(); len(visible) != 3 {
+		t.Errorf("Expected 3 visible messages but got %+v", visible)
+	}
+	if !reflect.DeepEqual(chatLog.ChatLogToml.Messages, messages) {
+		t.Errorf("Expected the chat log to keep %+v but got %+v", messages, chatLog.ChatLogToml.Messages)
+	}
+
+	tui.editLast()
+	if !strings.HasPrefix(tui.status, "Clear the input") {
+		t.Errorf("Expected to clear the input first but got %q", tui.status)
+	}
+
+	tui.input = nil
+	tui.editLast()
+	if string(tui.input) != "First" || tui.editFrom != 1 {
+		t.Errorf("Expected %q from 1 but got %q from %d", "First", string(tui.input), tui.editFrom)
+	}
+
+	// Quitting during an edit keeps the messages it would replace.
+	if err := tui.saveInput(); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	saved := &oax.ChatLog{FilePath: &filePath}
+	if err := saved.LoadLogMessage(); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if len(saved.ChatLogToml.Messages) != len(messages) || saved.ChatLogToml.Messages[4].Content != "Answer 2" {
+		t.Errorf("Expected %+v but got %+v", messages, saved.ChatLogToml.Messages)
+	}
+
+	tui.cancelEdit()
+	if tui.editing || len(tui.input) != 0 || len(tui.visibleMessages()) != len(messages) {
+		t.Errorf("Expected the edit to be canceled but got %q, %+v", string(tui.input), tui.visibleMessages())
+	}
+
+	empty := &chatTUI{chatLog: &oax.ChatLog{}}
+	empty.editLast()
+	if empty.editing || empty.status != "No message to edit." {
+		t.Errorf("Expected no message to edit but got %q", empty.status)
+	}
+}
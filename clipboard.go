@@ -0,0 +1,45 @@
+package oax
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ReadClipboard returns the text in the clipboard using the command of the
+// platform: pbpaste, PowerShell, wl-paste, xclip or xsel.
+func ReadClipboard() (string, error) {
+	var commands [][]string
+
+	switch runtime.GOOS {
+	case "darwin":
+		commands = [][]string{{"pbpaste"}}
+	case "windows":
+		commands = [][]string{{"powershell", "-NoProfile", "-Command", "Get-Clipboard"}}
+	default:
+		if os.Getenv("WAYLAND_DISPLAY") != "" {
+			commands = append(commands, []string{"wl-paste", "--no-newline"})
+		}
+		commands = append(commands,
+			[]string{"xclip", "-selection", "clipboard", "-o"},
+			[]string{"xsel", "--clipboard", "--output"},
+		)
+	}
+
+	for _, command := range commands {
+		if _, err := exec.LookPath(command[0]); err != nil {
+			continue
+		}
+
+		out, err := exec.Command(command[0], command[1:]...).Output()
+		if err != nil {
+			return "", err
+		}
+
+		return strings.TrimRight(string(out), "\r\n"), nil
+	}
+
+	return "", errors.New("cannot read the clipboard: no clipboard command found")
+}
@@ -10,8 +10,9 @@ import (
 )
 
 type Globals struct {
-	Profile string          `short:"p" name:"profile" help:"Specify the profile."`
-	Version cli.VersionFlag `short:"v" name:"version" help:"Print the version."`
+	ConfigDir string          `name:"config-dir" placeholder:"DIR" help:"Read settings.toml and profiles.toml from this directory."`
+	Profile   string          `short:"p" name:"profile" help:"Specify the profile."`
+	Version   cli.VersionFlag `short:"v" name:"version" help:"Print the version."`
 }
 
 var CLI struct {
@@ -19,90 +20,536 @@ var CLI struct {
 	Config struct {
 		Settings bool `help:"Open the settings configuration file."`
 		Profiles bool `help:"Open the profiles configuration file."`
+		Open     struct {
+		} `cmd:"" default:"1" hidden:"" help:"Open the configuration files in the editor."`
+		Show struct {
+			Resolved bool `help:"Also print where each value came from: default, global file, project file, environment variable or flag."`
+		} `cmd:"" help:"Print the effective configuration."`
+		StoreKey struct {
+			Profile string `arg:"" help:"Profile name."`
+			Delete  bool   `short:"d" help:"Remove the API key of the profile from the key store."`
+		} `cmd:"" help:"Save the API key of a profile, read without echo, in the encrypted key store. Use it with apiKeyStore = true in the profile."`
+		Validate struct {
+		} `cmd:"" help:"Check the configuration files for unknown keys, wrong types, duplicate or multiple default profiles, invalid template roles and a missing editor."`
 	} `cmd:"" help:"Provides a feature to check the OAX configuration settings"`
+	ProfileCmd struct {
+		List struct {
+		} `cmd:"" help:"List profiles with masked API keys. The default profile is marked with *."`
+		Show struct {
+			Name string `arg:"" help:"Profile name."`
+		} `cmd:"" help:"Print a profile with its API key masked."`
+		Add struct {
+			Name           string `arg:"" help:"Profile name."`
+			Description    string `help:"Description of the profile."`
+			OrganizationID string `name:"organization-id" help:"OpenAI Organization ID."`
+			APIKeyEnv      string `name:"api-key-env" placeholder:"NAME" help:"Read the API key from this environment variable instead of prompting for it."`
+			APIKeyCommand  string `name:"api-key-command" placeholder:"COMMAND" help:"Read the API key from the output of this command instead of prompting for it."`
+			Store          bool   `help:"Save the API key in the encrypted key store instead of profiles.toml."`
+			Default        bool   `short:"d" help:"Make it the default profile."`
+		} `cmd:"" help:"Add a profile. The API key is prompted for without echo."`
+		Remove struct {
+			Name string `arg:"" help:"Profile name."`
+		} `cmd:"" help:"Remove a profile."`
+		SetDefault struct {
+			Name string `arg:"" help:"Profile name."`
+		} `cmd:"" help:"Make a profile the only default profile."`
+		Test struct {
+			Name string `arg:"" optional:"" help:"Profile name. The default profile when omitted."`
+		} `cmd:"" help:"Check the API key of a profile and list the chat models it can use. The list is cached to validate --model."`
+	} `cmd:"" name:"profile" help:"Manage profiles in profiles.toml."`
 	Chat struct {
-		Model        string  `short:"m" help:"Specify the ID of the model to use gpt-4, gpt-4-0314, gpt-4-32k, gpt-4-32k-0314, gpt-3.5-turbo, gpt-3.5-turbo-0301(default gpt-3.5-turbo)"`
-		File         *string `short:"f" help:"Specify the chat history file with the full path."`
-		TemplateName string  `short:"t" help:"Specify a chat template name."`
-		Continue     bool    `short:"c" help:"Search your past chat history files with fuzzy matching and resume the chat from where you left off. This is an easier way to resume than using the --file option."`
+		Model        string            `short:"m" help:"Specify the model ID or an alias from [models.<alias>] in the settings. See oax models (default gpt-3.5-turbo)."`
+		File         *string           `short:"f" help:"Specify the chat history file with the full path."`
+		TemplateName cli.TemplateFlag  `short:"t" placeholder:"NAME" help:"Specify a chat template name. Select one with fuzzy matching when the name is omitted."`
+		Var          map[string]string `help:"Set a template variable, e.g. --var Lang=Go. Variables without a value or default are prompted for."`
+		TemplateMode string            `enum:"system,append" default:"system" help:"How -t applies a template when resuming with --continue or --file: system replaces the system messages of the conversation with those of the template, append adds its other messages as the next turn."`
+		TUI          bool              `name:"tui" help:"Chat in a full-screen terminal UI instead of the editor. Ctrl+S sends, Ctrl+R regenerates, Ctrl+E edits the last message, Ctrl+B branches the chat log and Ctrl+O switches the model."`
+		Render       string            `enum:",markdown,raw" default:"" help:"How answers are written to the terminal, overriding output.render in settings: markdown formats them and highlights code blocks, raw writes them as they are."`
+		Continue     bool              `short:"c" help:"Search your past chat history files with fuzzy matching and resume the chat from where you left off. This is an easier way to resume than using the --file option."`
+		Search       string            `short:"s" help:"With --continue, only show chat history files whose messages match this full-text query."`
+		Semantic     bool              `help:"With --search, rank chat history files by semantic similarity to the query using embeddings."`
+		Archived     bool              `short:"a" help:"With --continue, also show archived chat history files."`
 	} `cmd:"" help:"Provides a dialogue function like chat.openai.com."`
+	Export struct {
+		Files  []string `arg:"" optional:"" help:"Chat log files to export. When omitted, select them from your chat history with fuzzy matching (Tab to select multiple)."`
+		Format string   `short:"F" enum:"markdown,html,json,finetune" default:"markdown" help:"Output format: markdown, html, json or finetune (OpenAI fine-tuning JSONL)."`
+		Role   []string `short:"r" help:"Only export messages with the given roles (e.g. -r user -r assistant)."`
+		Output string   `short:"o" help:"Write the export to this file instead of stdout."`
+	} `cmd:"" help:"Export chat logs to Markdown, HTML, JSON or fine-tuning JSONL."`
+	Import struct {
+		Files  []string `arg:"" help:"Files to import: conversations.json or the zip archive from the ChatGPT data export, or JSONL with one {\"messages\": [...]} per line. Use - for stdin."`
+		Format string   `short:"F" enum:",chatgpt,jsonl" default:"" help:"Input format: chatgpt or jsonl (default: detected from the content)."`
+		DryRun bool     `short:"n" help:"Print the chat log files that would be written without writing them."`
+	} `cmd:"" help:"Import conversations from a ChatGPT export or JSONL into chat logs."`
+	Search struct {
+		Query    string   `arg:"" help:"Words and \"quoted phrases\" that must all appear in a conversation (case-insensitive), or a regular expression with --regex."`
+		Regex    bool     `short:"e" help:"Treat the query as a regular expression."`
+		Semantic bool     `help:"Rank conversations by semantic similarity to the query using embeddings from the API."`
+		Role     []string `short:"r" help:"Only search messages with the given roles."`
+		Since    string   `help:"Only search chat logs modified on or after this date (YYYY-MM-DD)."`
+		Until    string   `help:"Only search chat logs modified on or before this date (YYYY-MM-DD)."`
+		Limit    int      `short:"n" help:"Maximum number of chat logs to show."`
+	} `cmd:"" help:"Search the contents of your chat history."`
+	Template struct {
+		List struct {
+		} `cmd:"" help:"List templates with the file defining each. The default template is marked with *."`
+		Show struct {
+			Name string `arg:"" help:"Template name."`
+		} `cmd:"" help:"Print a template with its variables and messages."`
+		New struct {
+			Name    string `arg:"" help:"Template name, also the file name."`
+			Project bool   `help:"Create the template in the .oax/templates directory of the project instead of the config directory."`
+		} `cmd:"" help:"Create a template file and open it in the editor."`
+		Edit struct {
+			Name string `arg:"" help:"Template name."`
+		} `cmd:"" help:"Open the file defining a template in the editor."`
+	} `cmd:"" help:"Manage chat templates in settings.toml and the templates directories."`
+	Models struct {
+		All     bool `help:"List all models, not only chat models."`
+		Aliases bool `help:"List the model aliases of the settings without calling the API."`
+	} `cmd:"" help:"List the models of the provider and the model aliases that refer to them."`
+	Completion struct {
+		Shell string `arg:"" enum:"bash,zsh,fish" help:"Shell: bash, zsh or fish."`
+	} `cmd:"" help:"Print a shell completion script. e.g. source <(oax completion bash), or oax completion fish > ~/.config/fish/completions/oax.fish"`
+	Log struct {
+		List struct {
+			Archived bool     `short:"a" help:"Include archived chat logs."`
+			Tag      []string `short:"t" help:"Only list chat logs with all of the given tags."`
+		} `cmd:"" help:"List chat logs with their title, date, model, number of turns and tags."`
+		Show struct {
+			Files []string `arg:"" optional:"" help:"Chat log paths or names in the chat log directory. Select one with fuzzy matching when omitted."`
+		} `cmd:"" help:"Print chat logs as Markdown."`
+		Rename struct {
+			File string `arg:"" help:"Chat log path or name in the chat log directory."`
+			Name string `arg:"" help:"New file name. The extension of the chat log is kept when omitted."`
+		} `cmd:"" help:"Rename a chat log."`
+		Rm struct {
+			Files []string `arg:"" optional:"" help:"Chat log paths or names in the chat log directory. Select one with fuzzy matching when omitted."`
+		} `cmd:"" help:"Move chat logs to the trash."`
+		Undo struct {
+		} `cmd:"" help:"Restore the chat log most recently moved to the trash."`
+		Archive struct {
+			Files   []string `arg:"" optional:"" help:"Chat log paths or names in the chat log directory. Select one with fuzzy matching when omitted."`
+			Restore bool     `short:"r" help:"Move archived chat logs back out of the archive."`
+		} `cmd:"" help:"Move chat logs to the archive, which oax chat -c does not show by default."`
+		Tag struct {
+			File   string   `arg:"" help:"Chat log path or name in the chat log directory."`
+			Tags   []string `arg:"" optional:"" help:"Tags to add."`
+			Delete []string `short:"d" help:"Tags to remove."`
+		} `cmd:"" help:"Add or remove tags of a chat log and print its tags."`
+	} `cmd:"" help:"Manage chat logs."`
+}
+
+var kongOptions = []kong.Option{
+	kong.Name("oax"),
+	kong.Description("CLI for OpenAI's ChatGPT."),
 }
 
 func main() {
-	config, err := oax.GetConfig()
+	if len(os.Args) > 1 && os.Args[1] == cli.CompleteCommand {
+		complete(os.Args[2:])
+
+		return
+	}
+
+	kontext := kong.Parse(&CLI, kongOptions...)
+
+	configOption := &oax.ConfigOption{
+		ConfigDir: CLI.ConfigDir,
+		Profile:   CLI.Profile,
+		Model:     CLI.Chat.Model,
+	}
+
+	// Validation only reads the files, so it can report problems that make
+	// loading the configuration fail.
+	switch kontext.Command() {
+	case "config validate":
+		err := cli.ConfigValidate(configOption)
+		if err != nil {
+			os.Exit(1)
+		}
+
+		return
+	case "completion <shell>":
+		err := cli.Completion(CLI.Completion.Shell)
+		if err != nil {
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	config, err := oax.GetConfig(configOption)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v", err)
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+
+		// The checks only read the files, so they can explain why the
+		// configuration cannot be loaded.
+		diagnostics, _ := oax.ValidateConfig(configOption)
+		for _, diagnostic := range diagnostics {
+			fmt.Fprintf(os.Stderr, "%s\n", diagnostic)
+		}
+
+		os.Exit(1)
+	}
+	for _, warning := range config.Warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
 	}
 
-	kontext := kong.Parse(&CLI,
-		kong.Name("oax"),
-		kong.Description("CLI for OpenAI's ChatGPT."),
-	)
+	// The configuration commands work without a valid profile so that it
+	// can be fixed.
+	switch kontext.Command() {
+	case "config open":
+		err := cli.Config(config.Settings.Setting.Editor, CLI.Config.Settings, CLI.Config.Profiles)
+		if err != nil {
+			os.Exit(1)
+		}
 
-	var useProfile oax.Profile
-	if CLI.Profile == "" {
-		for _, profile := range config.Profiles {
-			if profile.Default {
-				useProfile = profile
-			}
+		return
+	case "config show":
+		err := cli.ConfigShow(config, CLI.Config.Show.Resolved)
+		if err != nil {
+			os.Exit(1)
 		}
-	} else {
-		for _, profile := range config.Profiles {
-			if profile.Name == CLI.Profile {
-				useProfile = profile
-			}
+
+		return
+	case "profile list":
+		err := cli.ProfileList(config)
+		if err != nil {
+			os.Exit(1)
+		}
+
+		return
+	case "profile show <name>":
+		err := cli.ProfileShow(config, CLI.ProfileCmd.Show.Name)
+		if err != nil {
+			os.Exit(1)
+		}
+
+		return
+	case "profile add <name>":
+		err := cli.ProfileAdd(config, &cli.ProfileAddOption{
+			Name:           CLI.ProfileCmd.Add.Name,
+			Description:    CLI.ProfileCmd.Add.Description,
+			OrganizationID: CLI.ProfileCmd.Add.OrganizationID,
+			APIKeyEnv:      CLI.ProfileCmd.Add.APIKeyEnv,
+			APIKeyCommand:  CLI.ProfileCmd.Add.APIKeyCommand,
+			Store:          CLI.ProfileCmd.Add.Store,
+			Default:        CLI.ProfileCmd.Add.Default,
+		})
+		if err != nil {
+			os.Exit(1)
 		}
+
+		return
+	case "profile remove <name>":
+		err := cli.ProfileRemove(config, CLI.ProfileCmd.Remove.Name)
+		if err != nil {
+			os.Exit(1)
+		}
+
+		return
+	case "profile set-default <name>":
+		err := cli.ProfileSetDefault(CLI.ProfileCmd.SetDefault.Name)
+		if err != nil {
+			os.Exit(1)
+		}
+
+		return
+	case "profile test", "profile test <name>":
+		err := cli.ProfileTest(config, CLI.ProfileCmd.Test.Name)
+		if err != nil {
+			os.Exit(1)
+		}
+
+		return
+	case "template list":
+		err := cli.TemplateList(config)
+		if err != nil {
+			os.Exit(1)
+		}
+
+		return
+	case "template show <name>":
+		err := cli.TemplateShow(config, CLI.Template.Show.Name)
+		if err != nil {
+			os.Exit(1)
+		}
+
+		return
+	case "template new <name>":
+		err := cli.TemplateNew(config, CLI.Template.New.Name, CLI.Template.New.Project)
+		if err != nil {
+			os.Exit(1)
+		}
+
+		return
+	case "template edit <name>":
+		err := cli.TemplateEdit(config, CLI.Template.Edit.Name)
+		if err != nil {
+			os.Exit(1)
+		}
+
+		return
+	case "config store-key <profile>":
+		err := cli.ConfigStoreKey(config, CLI.Config.StoreKey.Profile, CLI.Config.StoreKey.Delete)
+		if err != nil {
+			os.Exit(1)
+		}
+
+		return
 	}
 
-	if useProfile.Name == "" {
-		fmt.Fprintf(os.Stderr, "invalid profile %s. Please check settings using `oax config --profiles`.\n", CLI.Profile)
+	model, err := config.ResolveModel()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
 
 		os.Exit(1)
 	}
 
-	var useTemplate *oax.ChatTemplate
-	if CLI.Chat.TemplateName != "" {
-		for _, template := range config.Settings.Chat.Templates {
-			if CLI.Chat.TemplateName == template.Name {
-				useTemplate = &template
-			}
+	useProfile, err := config.SelectProfile()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\nPlease check settings using `oax profile list`.\n", err)
+
+		os.Exit(1)
+	}
+
+	// The API key is resolved only by the commands that call the API, as
+	// apiKeyCommand and the key store may prompt.
+	resolveAPIKey := func() {
+		if err := config.ResolveAPIKey(&useProfile); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\nPlease check settings using `oax profile list`.\n", err)
+
+			os.Exit(1)
 		}
 	}
 
 	switch kontext.Command() {
-	case "config":
-		err := cli.Config(config.Settings.Setting.Editor, CLI.Config.Settings, CLI.Config.Profiles)
+	case "chat":
+		resolveAPIKey()
+
+		templateName := CLI.Chat.TemplateName.Name
+		resuming := CLI.Chat.File != nil || CLI.Chat.Continue
+		if templateName == "" && !CLI.Chat.TemplateName.Pick && !resuming {
+			templateName = config.Settings.Chat.DefaultTemplate
+		}
+
+		var useTemplate *oax.ChatTemplate
+		if CLI.Chat.TemplateName.Pick {
+			useTemplate, err = cli.PickTemplate(config.Settings.Chat.Templates)
+		} else if templateName != "" {
+			useTemplate, err = oax.FindTemplate(config.Settings.Chat.Templates, templateName)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s\n", err)
+			}
+		}
 		if err != nil {
 			os.Exit(1)
 		}
-	case "chat":
-		var model string
-		defaultModel := "gpt-3.5-turbo"
 
-		if CLI.Chat.Model == "" && config.Settings.Chat.Model == "" {
-			model = defaultModel
-		} else if CLI.Chat.Model != "" && config.Settings.Chat.Model == "" {
-			model = CLI.Chat.Model
-		} else if CLI.Chat.Model == "" && config.Settings.Chat.Model != "" {
-			model = config.Settings.Chat.Model
-		} else if CLI.Chat.Model != "" && config.Settings.Chat.Model != "" {
-			model = CLI.Chat.Model
+		modelCache, err := oax.OpenModelCache(config.ConfigDir)
+		if err == nil {
+			err = modelCache.ValidateModel(useProfile.Name, model.Model)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", err)
+		}
+
+		render := config.Settings.Output.Render
+		if CLI.Chat.Render != "" {
+			render = CLI.Chat.Render
 		}
 
-		err := cli.Chat(&cli.ChatOption{
+		err = cli.Chat(&cli.ChatOption{
 			APIKey:         useProfile.ApiKey,
 			OrganizationID: useProfile.OrganizationID,
 			Editor:         config.Settings.Setting.Editor,
-			Model:          model,
+			Model:          model.Model,
+			Params:         model.Params,
+			ContextSize:    model.ContextSize,
 			ChatLogDir:     config.Settings.Setting.ChatLogDir,
+			ChatLogFormat:  config.Settings.Setting.ChatLogFormat,
 			FileNameFormat: config.Settings.Chat.FileNameFormat,
 			File:           CLI.Chat.File,
 			Template:       useTemplate,
+			TemplateMode:   CLI.Chat.TemplateMode,
+			TUI:            CLI.Chat.TUI,
+			ModelChoices:   config.ModelChoices(useProfile.Name),
+			Render:         render,
+			Theme:          config.Settings.Output.Theme,
+			Vars:           CLI.Chat.Var,
 			Continue:       CLI.Chat.Continue,
+			Archived:       CLI.Chat.Archived,
+			ListOption:     config.Settings.Setting.ListOption(),
+			Search:         CLI.Chat.Search,
+			Semantic:       CLI.Chat.Semantic,
+			SemanticIndex:  config.Settings.Search.Semantic,
+			EmbeddingModel: config.Settings.Search.EmbeddingModel,
+			DataDir:        config.DataDir,
 		})
 		if err != nil {
 			os.Exit(1)
 		}
+	case "models":
+		if !CLI.Models.Aliases {
+			resolveAPIKey()
+		}
+
+		err := cli.Models(&cli.ModelsOption{
+			APIKey:         useProfile.ApiKey,
+			OrganizationID: useProfile.OrganizationID,
+			ProfileName:    useProfile.Name,
+			ConfigDir:      config.ConfigDir,
+			Aliases:        config.Settings.Models,
+			All:            CLI.Models.All,
+			AliasesOnly:    CLI.Models.Aliases,
+		})
+		if err != nil {
+			os.Exit(1)
+		}
+	case "export", "export <files>":
+		err := cli.Export(&cli.ExportOption{
+			ChatLogDir: config.Settings.Setting.ChatLogDir,
+			Files:      CLI.Export.Files,
+			Format:     CLI.Export.Format,
+			Roles:      CLI.Export.Role,
+			Output:     CLI.Export.Output,
+			ListOption: config.Settings.Setting.ListOption(),
+		})
+		if err != nil {
+			os.Exit(1)
+		}
+	case "import <files>":
+		err := cli.Import(&cli.ImportOption{
+			ChatLogDir:     config.Settings.Setting.ChatLogDir,
+			ChatLogFormat:  config.Settings.Setting.ChatLogFormat,
+			FileNameFormat: config.Settings.Chat.FileNameFormat,
+			Files:          CLI.Import.Files,
+			Format:         CLI.Import.Format,
+			DryRun:         CLI.Import.DryRun,
+		})
+		if err != nil {
+			os.Exit(1)
+		}
+	case "log list":
+		err := cli.LogList(&cli.LogOption{
+			ChatLogDir: config.Settings.Setting.ChatLogDir,
+			DataDir:    config.DataDir,
+			ListOption: config.Settings.Setting.ListOption(),
+			Archived:   CLI.Log.List.Archived,
+			Tags:       CLI.Log.List.Tag,
+		})
+		if err != nil {
+			os.Exit(1)
+		}
+	case "log show", "log show <files>":
+		err := cli.LogShow(&cli.LogOption{
+			ChatLogDir: config.Settings.Setting.ChatLogDir,
+			DataDir:    config.DataDir,
+			ListOption: config.Settings.Setting.ListOption(),
+			Files:      CLI.Log.Show.Files,
+		})
+		if err != nil {
+			os.Exit(1)
+		}
+	case "log rename <file> <name>":
+		err := cli.LogRename(&cli.LogOption{
+			ChatLogDir: config.Settings.Setting.ChatLogDir,
+			DataDir:    config.DataDir,
+			ListOption: config.Settings.Setting.ListOption(),
+			Files:      []string{CLI.Log.Rename.File},
+		}, CLI.Log.Rename.Name)
+		if err != nil {
+			os.Exit(1)
+		}
+	case "log rm", "log rm <files>":
+		err := cli.LogRemove(&cli.LogOption{
+			ChatLogDir: config.Settings.Setting.ChatLogDir,
+			DataDir:    config.DataDir,
+			ListOption: config.Settings.Setting.ListOption(),
+			Files:      CLI.Log.Rm.Files,
+		})
+		if err != nil {
+			os.Exit(1)
+		}
+	case "log undo":
+		err := cli.LogUndo(&cli.LogOption{
+			ChatLogDir: config.Settings.Setting.ChatLogDir,
+			DataDir:    config.DataDir,
+			ListOption: config.Settings.Setting.ListOption(),
+		})
+		if err != nil {
+			os.Exit(1)
+		}
+	case "log archive", "log archive <files>":
+		err := cli.LogArchive(&cli.LogOption{
+			ChatLogDir: config.Settings.Setting.ChatLogDir,
+			DataDir:    config.DataDir,
+			ListOption: config.Settings.Setting.ListOption(),
+			Files:      CLI.Log.Archive.Files,
+		}, CLI.Log.Archive.Restore)
+		if err != nil {
+			os.Exit(1)
+		}
+	case "log tag <file>", "log tag <file> <tags>":
+		err := cli.LogTag(&cli.LogOption{
+			ChatLogDir: config.Settings.Setting.ChatLogDir,
+			DataDir:    config.DataDir,
+			ListOption: config.Settings.Setting.ListOption(),
+			Files:      []string{CLI.Log.Tag.File},
+		}, CLI.Log.Tag.Tags, CLI.Log.Tag.Delete)
+		if err != nil {
+			os.Exit(1)
+		}
+	case "search <query>":
+		if CLI.Search.Semantic {
+			resolveAPIKey()
+		}
+
+		err := cli.Search(&cli.SearchOption{
+			DataDir:        config.DataDir,
+			ChatLogDir:     config.Settings.Setting.ChatLogDir,
+			Query:          CLI.Search.Query,
+			Regex:          CLI.Search.Regex,
+			Semantic:       CLI.Search.Semantic,
+			Roles:          CLI.Search.Role,
+			Since:          CLI.Search.Since,
+			Until:          CLI.Search.Until,
+			Limit:          CLI.Search.Limit,
+			APIKey:         useProfile.ApiKey,
+			OrganizationID: useProfile.OrganizationID,
+			EmbeddingModel: config.Settings.Search.EmbeddingModel,
+		})
+		if err != nil {
+			os.Exit(1)
+		}
+	}
+
+}
+
+// complete prints the completion candidates for the command line words in
+// args. The configuration is loaded with --config-dir and --profile from
+// args, and ignored when it cannot be loaded.
+func complete(args []string) {
+	parser, err := kong.New(&CLI, kongOptions...)
+	if err != nil {
+		os.Exit(1)
+	}
+
+	configOption := &oax.ConfigOption{}
+	for i := 0; i+1 < len(args); i++ {
+		switch args[i] {
+		case "--config-dir":
+			configOption.ConfigDir = args[i+1]
+		case "-p", "--profile":
+			configOption.Profile = args[i+1]
+		}
+	}
+
+	config, err := oax.GetConfig(configOption)
+	if err != nil {
+		config = nil
 	}
 
+	cli.Complete(parser.Model, config, args)
 }
@@ -2,35 +2,88 @@ package oax
 
 import (
 	"fmt"
-	"io/ioutil"
 	"os"
 	"os/exec"
-	"os/user"
 	"path/filepath"
 	"runtime"
+	"strings"
 
 	"github.com/pelletier/go-toml"
 )
 
 type Settings struct {
-	Setting Setting `toml:"setting"`
-	Chat    Chat    `toml:"chat"`
+	Setting Setting               `toml:"setting"`
+	Chat    Chat                  `toml:"chat"`
+	Search  Search                `toml:"search"`
+	Output  Output                `toml:"output"`
+	Models  map[string]ModelAlias `toml:"models"`
 }
 
 type Setting struct {
-	Editor     string `toml:"editor"`
-	ChatLogDir string `toml:"chatLogDir"`
+	Editor           string `toml:"editor"`
+	ChatLogDir       string `toml:"chatLogDir"`
+	ChatLogFormat    string `toml:"chatLogFormat"`
+	ChatLogSort      string `toml:"chatLogSort"`
+	ChatLogRecursive *bool  `toml:"chatLogRecursive"`
+}
+
+// ListOption returns how chat logs are listed for the resume picker and
+// the log commands.
+func (s Setting) ListOption() ListOption {
+	recursive := true
+	if s.ChatLogRecursive != nil {
+		recursive = *s.ChatLogRecursive
+	}
+
+	return ListOption{
+		Sort:      s.ChatLogSort,
+		Recursive: recursive,
+	}
 }
 
 type Chat struct {
-	Model          string         `toml:"model"`
-	Templates      []ChatTemplate `toml:"templates"`
-	FileNameFormat string         `toml:"fileNameFormat"`
+	Model           string         `toml:"model"`
+	DefaultTemplate string         `toml:"defaultTemplate"`
+	Templates       []ChatTemplate `toml:"templates"`
+	FileNameFormat  string         `toml:"fileNameFormat"`
+}
+
+type Search struct {
+	Semantic       bool   `toml:"semantic"`
+	EmbeddingModel string `toml:"embeddingModel"`
+}
+
+// Output is how answers are written to the terminal.
+type Output struct {
+	// Render is RenderMarkdown or RenderRaw. Markdown is only rendered when
+	// stdout is a terminal.
+	Render string `toml:"render"`
+	// Theme is the chroma style used to highlight code blocks.
+	Theme string `toml:"theme"`
 }
 
 type ChatTemplate struct {
-	Name     string    `toml:"name"`
-	Messages []Message `toml:"messages"`
+	Name        string `toml:"name"`
+	Description string `toml:"description"`
+	// Extends and Include name templates whose params and messages come
+	// before those of this template. See ResolveTemplates.
+	Extends string   `toml:"extends"`
+	Include []string `toml:"include"`
+	// Params declares the variables used in the messages. Messages are
+	// only rendered when there are params or Expand is set. See Render.
+	Params   []TemplateParam `toml:"params"`
+	Expand   bool            `toml:"expand"`
+	Messages []Message       `toml:"messages"`
+	// FilePath is the settings or template file defining the template.
+	FilePath string `toml:"-"`
+	// err is why the extends or include of the template cannot be
+	// resolved. See FindTemplate.
+	err error `toml:"-"`
+	// projectRoot is the root of the project the template, or one it
+	// extends or includes, comes from. Such a template may come from a
+	// repository that is not trusted, so it can only include files of the
+	// project and cannot read environment variables. See Render.
+	projectRoot string `toml:"-"`
 }
 
 type Message struct {
@@ -39,9 +92,14 @@ type Message struct {
 }
 
 type Profile struct {
-	Name           string `toml:"name"`
-	Description    string `toml:"description"`
-	ApiKey         string `toml:"apiKey"`
+	Name        string `toml:"name"`
+	Description string `toml:"description"`
+	ApiKey      string `toml:"apiKey"`
+	// ApiKeyEnv, ApiKeyCommand and ApiKeyStore are alternatives to writing
+	// the API key in profiles.toml, used in this order when ApiKey is empty.
+	ApiKeyEnv      string `toml:"apiKeyEnv"`
+	ApiKeyCommand  string `toml:"apiKeyCommand"`
+	ApiKeyStore    bool   `toml:"apiKeyStore"`
 	OrganizationID string `toml:"organizationId"`
 	Default        bool   `toml:"default"`
 }
@@ -51,74 +109,226 @@ type ProfileToml struct {
 }
 
 type Config struct {
+	ConfigDir string
+	// DataDir holds the chat logs, unless chatLogDir is set, and the search
+	// indexes.
+	DataDir string
+	// Project is the project found from the working directory, or nil.
+	Project  *Project
 	Profiles []Profile
 	Settings Settings
+	// ProfileName is the profile selected with --profile or OAX_PROFILE.
+	ProfileName string
+	// APIKey is the API key given with OAX_API_KEY.
+	APIKey string
+	// Sources maps configuration keys to where their value came from.
+	Sources map[string]string
+	// Warnings are problems that did not stop the configuration from
+	// loading.
+	Warnings []string
+	keyStore *KeyStore
 }
 
 var (
+	configDirPath         string
 	settingFilePath       string
 	profileFilePath       string
 	chatLogDirDefaultPath string
 )
 
-func init() {
-	configDir, err := getConfigDir()
-	if err != nil {
-		panic(err)
-	}
-
+// setConfigDir sets the paths of the configuration files in configDir.
+func setConfigDir(configDir string) {
+	configDirPath = configDir
 	settingFilePath = filepath.Join(configDir, "settings.toml")
 	profileFilePath = filepath.Join(configDir, "profiles.toml")
-	chatLogDirDefaultPath = filepath.Join(configDir, "chat-log")
 }
 
-func GetConfig() (*Config, error) {
-	setting, err := loadSetting()
+func GetConfig(opt *ConfigOption) (*Config, error) {
+	var flagConfigDir string
+	if opt != nil {
+		flagConfigDir = opt.ConfigDir
+	}
+
+	configDir, configDirSource, err := getConfigDir(flagConfigDir)
+	if err != nil {
+		return nil, fmt.Errorf("error get config dir: %w", err)
+	}
+	err = os.MkdirAll(configDir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("error create config dir: %w", err)
+	}
+	setConfigDir(configDir)
+
+	config := &Config{
+		ConfigDir: configDir,
+		Settings:  *defaultSettings(),
+		Sources:   map[string]string{},
+	}
+	if configDirSource != SourceDefault {
+		config.Sources["configDir"] = configDirSource
+	}
+
+	dataDir, dataDirSource, err := getDataDir(configDir, configDirSource)
+	if err != nil {
+		return nil, fmt.Errorf("error get data dir: %w", err)
+	}
+	config.DataDir = dataDir
+	if dataDirSource != SourceDefault {
+		config.Sources["dataDir"] = dataDirSource
+	}
+
+	chatLogDir, chatLogDirSource := getChatLogDirDefault(configDir, dataDir, dataDirSource)
+	chatLogDirDefaultPath = chatLogDir
+	if chatLogDirSource != SourceDefault {
+		config.Sources["setting.chatLogDir"] = chatLogDirSource
+	}
+
+	setting, settingTree, err := loadSetting()
 	if err != nil {
 		return nil, fmt.Errorf("error load setting: %w", err)
 	}
+	mergeSettings(&config.Settings, setting)
+	markSources(config.Sources, settingTree, fmt.Sprintf("global file (%s)", settingFilePath), settingKeys())
+
+	// Template files that cannot be loaded are skipped and reported by the
+	// checks below.
+	templates, _ := LoadTemplateDir(filepath.Join(configDir, TemplatesDirName))
+	mergeTemplates(&config.Settings.Chat.Templates, templates)
 
 	profiles, err := loadProfiles()
 	if err != nil {
 		return nil, fmt.Errorf("error load profile: %w", err)
 	}
+	config.Profiles = profiles.Profiles
 
-	chatLogDir, err := createIfNotExistChatLogDir(setting.Setting.ChatLogDir)
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	project, err := FindProject(wd)
+	if err != nil {
+		return nil, fmt.Errorf("error find project: %w", err)
+	}
+	config.Project = project
+
+	if project != nil {
+		projectSetting, projectTree, err := project.loadSetting()
+		if err != nil {
+			return nil, fmt.Errorf("error load project setting %s: %w", project.SettingFilePath, err)
+		}
+
+		mergeProjectSettings(&config.Settings, projectSetting)
+		if project.ChatLogDir != "" {
+			config.Sources["setting.chatLogDir"] = fmt.Sprintf("project directory (%s)", filepath.Dir(project.ChatLogDir))
+		}
+		markSources(config.Sources, projectTree, fmt.Sprintf("project file (%s)", project.SettingFilePath), projectSettingKeys)
+
+		if ignored := ignoredProjectSettings(projectTree); len(ignored) > 0 {
+			config.Warnings = append(config.Warnings, fmt.Sprintf("%s: ignoring %s; a project can only set %s",
+				project.SettingFilePath, strings.Join(ignored, ", "), strings.Join(projectSettingKeys, ", ")))
+		}
+
+		templates, _ := LoadTemplateDir(filepath.Join(project.Root, ProjectDirName, TemplatesDirName))
+		setTemplateProjectRoot(templates, project.Root)
+		mergeTemplates(&config.Settings.Chat.Templates, templates)
+	}
+
+	// A template that cannot be resolved is only an error when it is used.
+	config.Settings.Chat.Templates, _ = ResolveTemplates(config.Settings.Chat.Templates)
+
+	// The checks of oax config validate are warnings here, such as a
+	// misspelled key that is otherwise ignored. Looking up the editor is
+	// left to oax config validate.
+	if diagnostics, err := validateConfig(opt, false); err == nil {
+		for _, diagnostic := range diagnostics {
+			config.Warnings = append(config.Warnings, diagnostic.String())
+		}
+	}
+
+	config.applyOverrides(opt)
+
+	chatLogDir, err = createIfNotExistChatLogDir(config.Settings.Setting.ChatLogDir)
 	if err != nil {
 		return nil, fmt.Errorf("error create chat log dir: %w", err)
 	}
 
-	setting.Setting.ChatLogDir = chatLogDir
+	config.Settings.Setting.ChatLogDir = chatLogDir
 
-	return &Config{
-		Settings: *setting,
-		Profiles: profiles.Profiles,
-	}, nil
+	return config, nil
 }
 
-func getConfigDir() (string, error) {
-	usr, err := user.Current()
+// getConfigDir returns the directory of settings.toml and profiles.toml
+// and where it came from: --config-dir, OAX_CONFIG_DIR, XDG_CONFIG_HOME or
+// the default of ~/.config/oax (%APPDATA%\oax on Windows).
+func getConfigDir(flagConfigDir string) (string, string, error) {
+	if flagConfigDir != "" {
+		configDir, err := replaceTildeWithHomedir(flagConfigDir)
+
+		return configDir, "flag --config-dir", err
+	}
+
+	if configDir := os.Getenv(EnvConfigDir); configDir != "" {
+		configDir, err := replaceTildeWithHomedir(configDir)
+
+		return configDir, envSource(EnvConfigDir), err
+	}
+
+	if xdgConfigHome := os.Getenv(EnvXDGConfigHome); filepath.IsAbs(xdgConfigHome) {
+		return filepath.Join(xdgConfigHome, "oax"), envSource(EnvXDGConfigHome), nil
+	}
+
+	homeDir, err := homeDir()
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
-	var configDir string
+	if runtime.GOOS == "windows" {
+		return filepath.Join(homeDir, "AppData", "Roaming", "oax"), SourceDefault, nil
+	}
+
+	return filepath.Join(homeDir, ".config", "oax"), SourceDefault, nil
+}
+
+// getDataDir returns the directory of chat logs and search indexes and
+// where it came from: XDG_DATA_HOME or ~/.local/share/oax. A config
+// directory given with --config-dir or OAX_CONFIG_DIR, and the config
+// directory on Windows, keep everything in one place.
+func getDataDir(configDir string, configDirSource string) (string, string, error) {
+	if configDirSource != SourceDefault && configDirSource != envSource(EnvXDGConfigHome) {
+		return configDir, configDirSource, nil
+	}
+
+	if xdgDataHome := os.Getenv(EnvXDGDataHome); filepath.IsAbs(xdgDataHome) {
+		return filepath.Join(xdgDataHome, "oax"), envSource(EnvXDGDataHome), nil
+	}
 
 	if runtime.GOOS == "windows" {
-		configDir = filepath.Join(usr.HomeDir, "AppData", "Roaming", "oax")
-	} else {
-		configDir = filepath.Join(usr.HomeDir, ".config", "oax")
+		return configDir, SourceDefault, nil
 	}
 
-	err = os.MkdirAll(configDir, 0755)
+	homeDir, err := homeDir()
 	if err != nil {
-		return "", err
+		return "", "", err
+	}
+
+	return filepath.Join(homeDir, ".local", "share", "oax"), SourceDefault, nil
+}
+
+// getChatLogDirDefault returns the chat log directory used when chatLogDir
+// is not set: chat-log in the data directory, except that an existing
+// chat-log directory in the config directory, where chat logs were saved
+// before, keeps being used.
+func getChatLogDirDefault(configDir string, dataDir string, dataDirSource string) (string, string) {
+	configChatLogDir := filepath.Join(configDir, "chat-log")
+	if dataDir != configDir && fileExists(configChatLogDir) {
+		return configChatLogDir, SourceDefault
 	}
 
-	return configDir, nil
+	return filepath.Join(dataDir, "chat-log"), dataDirSource
 }
 
-func loadSetting() (*Settings, error) {
+func loadSetting() (*Settings, *toml.Tree, error) {
 	_, err := os.Stat(settingFilePath)
 
 	var configTree *toml.Tree
@@ -127,29 +337,30 @@ func loadSetting() (*Settings, error) {
 		settingStr := `[setting]
   editor = "vim"`
 
-		err = ioutil.WriteFile(settingFilePath, []byte(settingStr), 0644)
+		err = writeFileAtomic(settingFilePath, []byte(settingStr), 0644, false)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		configTree, err = toml.Load(settingStr)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	} else {
 		configTree, err = toml.LoadFile(settingFilePath)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
 	setting := &Settings{}
 	err = configTree.Unmarshal(setting)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	setTemplateFilePath(setting.Chat.Templates, settingFilePath)
 
-	return setting, nil
+	return setting, configTree, nil
 }
 
 func loadProfiles() (*ProfileToml, error) {
@@ -161,10 +372,15 @@ func loadProfiles() (*ProfileToml, error) {
 		settingStr := `[[profiles]]
 name = "personal"
 apiKey = "sk-xxxx"
+# Instead of apiKey, read the key from an environment variable, the output
+# of a command, or the encrypted key store (oax config store-key personal).
+# apiKeyEnv = "OPENAI_API_KEY"
+# apiKeyCommand = "pass show openai"
+# apiKeyStore = true
 default = true
 `
 
-		err = ioutil.WriteFile(profileFilePath, []byte(settingStr), 0644)
+		err = writeFileAtomic(profileFilePath, []byte(settingStr), 0600, false)
 		if err != nil {
 			return nil, err
 		}
@@ -193,7 +409,8 @@ func createIfNotExistChatLogDir(chatLogDir string) (string, error) {
 	if chatLogDir == "" {
 		chatLogDir = chatLogDirDefaultPath
 	} else {
-		chatLogDir, err := replaceTildeWithHomedir(chatLogDir)
+		var err error
+		chatLogDir, err = replaceTildeWithHomedir(chatLogDir)
 		if err != nil {
 			return chatLogDir, err
 		}
@@ -0,0 +1,153 @@
+package oax
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestGetConfigDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("XDG directories are not used on Windows")
+	}
+
+	home, err := homeDir()
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+
+	testCases := []struct {
+		name               string
+		flag               string
+		configDirEnv       string
+		xdgConfigHome      string
+		xdgDataHome        string
+		expectedConfigDir  string
+		expectedChatLogDir string
+	}{
+		{"flag", "/flag", "/env", "/xdg-config", "/xdg-data", "/flag", "/flag/chat-log"},
+		{"env", "", "/env", "/xdg-config", "/xdg-data", "/env", "/env/chat-log"},
+		{"xdg", "", "", "/xdg-config", "/xdg-data", "/xdg-config/oax", "/xdg-data/oax/chat-log"},
+		{"relative xdg is ignored", "", "", "xdg-config", "xdg-data", filepath.Join(home, ".config", "oax"), ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(EnvConfigDir, tc.configDirEnv)
+			t.Setenv(EnvXDGConfigHome, tc.xdgConfigHome)
+			t.Setenv(EnvXDGDataHome, tc.xdgDataHome)
+
+			configDir, source, err := getConfigDir(tc.flag)
+			if err != nil {
+				t.Fatalf("Error: Return err func: %v", err)
+			}
+			if configDir != tc.expectedConfigDir {
+				t.Errorf("Expected %q but got %q", tc.expectedConfigDir, configDir)
+			}
+
+			if tc.expectedChatLogDir == "" {
+				return
+			}
+			dataDir, dataDirSource, err := getDataDir(configDir, source)
+			if err != nil {
+				t.Fatalf("Error: Return err func: %v", err)
+			}
+			chatLogDir, _ := getChatLogDirDefault(configDir, dataDir, dataDirSource)
+			if chatLogDir != tc.expectedChatLogDir {
+				t.Errorf("Expected %q but got %q", tc.expectedChatLogDir, chatLogDir)
+			}
+		})
+	}
+}
+
+func TestGetChatLogDirDefault(t *testing.T) {
+	configDir := t.TempDir()
+	dataDir := filepath.Join(t.TempDir(), "oax")
+
+	chatLogDir, _ := getChatLogDirDefault(configDir, dataDir, SourceDefault)
+	if expected := filepath.Join(dataDir, "chat-log"); chatLogDir != expected {
+		t.Errorf("Expected %q but got %q", expected, chatLogDir)
+	}
+
+	if err := os.Mkdir(filepath.Join(configDir, "chat-log"), 0755); err != nil {
+		t.Fatalf("Error: Cannot create dir: %v", err)
+	}
+
+	chatLogDir, _ = getChatLogDirDefault(configDir, dataDir, envSource(EnvXDGDataHome))
+	if expected := filepath.Join(configDir, "chat-log"); chatLogDir != expected {
+		t.Errorf("Expected the existing %q but got %q", expected, chatLogDir)
+	}
+}
+
+func TestGetConfig(t *testing.T) {
+	configDir := filepath.Join(t.TempDir(), "oax")
+	t.Setenv(EnvModel, "")
+	t.Setenv(EnvProfile, "")
+	t.Setenv(EnvAPIKey, "")
+
+	config, err := GetConfig(&ConfigOption{ConfigDir: configDir})
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+
+	if config.ConfigDir != configDir {
+		t.Errorf("Expected %q but got %q", configDir, config.ConfigDir)
+	}
+	for _, name := range []string{"settings.toml", "profiles.toml", "chat-log"} {
+		if _, err := os.Stat(filepath.Join(configDir, name)); err != nil {
+			t.Errorf("Expected %s to be created: %v", name, err)
+		}
+	}
+}
+
+func TestGetConfigSkipsBrokenTemplate(t *testing.T) {
+	configDir := filepath.Join(t.TempDir(), "oax")
+	t.Setenv(EnvModel, "")
+	t.Setenv(EnvProfile, "")
+	t.Setenv(EnvAPIKey, "")
+
+	templateDir := filepath.Join(configDir, TemplatesDirName)
+	if err := os.MkdirAll(templateDir, 0755); err != nil {
+		t.Fatalf("Error: Cannot create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(templateDir, "broken.toml"), []byte("messages = ["), 0644); err != nil {
+		t.Fatalf("Error: Cannot write file: %v", err)
+	}
+
+	config, err := GetConfig(&ConfigOption{ConfigDir: configDir})
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+
+	if len(config.Warnings) != 1 || !strings.Contains(config.Warnings[0], "broken.toml") {
+		t.Errorf("Expected a warning for broken.toml but got %q", config.Warnings)
+	}
+	if expected := filepath.Join(templateDir, "broken.toml"); config.FindTemplateFile("broken") != expected {
+		t.Errorf("Expected %q but got %q", expected, config.FindTemplateFile("broken"))
+	}
+}
+
+func TestGetConfigWarnsAboutUnknownKeys(t *testing.T) {
+	configDir := filepath.Join(t.TempDir(), "oax")
+	t.Setenv(EnvModel, "")
+	t.Setenv(EnvProfile, "")
+	t.Setenv(EnvAPIKey, "")
+
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatalf("Error: Cannot create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "settings.toml"), []byte("[setting]\n  chatlogdir = \"~/logs\"\n"), 0644); err != nil {
+		t.Fatalf("Error: Cannot write file: %v", err)
+	}
+
+	config, err := GetConfig(&ConfigOption{ConfigDir: configDir})
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+
+	if len(config.Warnings) != 1 || !strings.Contains(config.Warnings[0], "did you mean setting.chatLogDir?") {
+		t.Errorf("Expected a warning for setting.chatlogdir but got %q", config.Warnings)
+	}
+}
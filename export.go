@@ -0,0 +1,261 @@
+package oax
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+const (
+	ExportFormatMarkdown = "markdown"
+	ExportFormatHTML     = "html"
+	ExportFormatJSON     = "json"
+	ExportFormatFinetune = "finetune"
+)
+
+type ExportConversation struct {
+	Title    string        `json:"title"`
+	FilePath string        `json:"file"`
+	Model    string        `json:"model,omitempty"`
+	Messages []ChatMessage `json:"messages"`
+}
+
+// LoadChatLog reads the chat log at filePath in the format detected from
+// its extension.
+func LoadChatLog(filePath string) (*ChatLog, error) {
+	chatLog := &ChatLog{
+		ConfigDir: filepath.Dir(filePath),
+	}
+
+	if err := chatLog.LoadFile(filePath); err != nil {
+		return nil, err
+	}
+	if err := chatLog.LoadLogMessage(); err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+
+	return chatLog, nil
+}
+
+// Title returns the file name of the chat log without its extension.
+func (c *ChatLog) Title() string {
+	base := filepath.Base(*c.FilePath)
+
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// NewExportConversation returns the messages of chatLog with one of roles,
+// or all of them when roles is empty.
+func NewExportConversation(chatLog *ChatLog, roles []string) ExportConversation {
+	conversation := ExportConversation{
+		Title:    chatLog.Title(),
+		FilePath: *chatLog.FilePath,
+		Model:    chatLog.ChatLogToml.Model,
+		Messages: []ChatMessage{},
+	}
+
+	for _, message := range chatLog.ChatLogToml.Messages {
+		if len(roles) > 0 && !containsString(roles, message.Role) {
+			continue
+		}
+		conversation.Messages = append(conversation.Messages, message)
+	}
+
+	return conversation
+}
+
+func Export(w io.Writer, conversations []ExportConversation, format string) error {
+	switch format {
+	case ExportFormatMarkdown, "":
+		return exportMarkdown(w, conversations)
+	case ExportFormatHTML:
+		return exportHTML(w, conversations)
+	case ExportFormatJSON:
+		return exportJSON(w, conversations)
+	case ExportFormatFinetune:
+		return exportFinetune(w, conversations)
+	default:
+		return fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+func exportMarkdown(w io.Writer, conversations []ExportConversation) error {
+	var builder strings.Builder
+
+	for i, conversation := range conversations {
+		if i > 0 {
+			builder.WriteString("\n---\n\n")
+		}
+		builder.WriteString("# " + conversation.Title + "\n")
+
+		for _, message := range conversation.Messages {
+			builder.WriteString("\n### " + message.Role + "\n\n")
+			builder.WriteString(message.Content + "\n")
+		}
+	}
+
+	_, err := io.WriteString(w, builder.String())
+
+	return err
+}
+
+func exportJSON(w io.Writer, conversations []ExportConversation) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(conversations)
+}
+
+// exportFinetune writes one {"messages": [...]} object per conversation,
+// the layout expected by the OpenAI fine-tuning API.
+func exportFinetune(w io.Writer, conversations []ExportConversation) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+
+	for _, conversation := range conversations {
+		if len(conversation.Messages) == 0 {
+			continue
+		}
+
+		err := encoder.Encode(struct {
+			Messages []ChatMessage `json:"messages"`
+		}{conversation.Messages})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var exportHTMLTemplate = template.Must(template.New("export").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{ .Title }}</title>
+<style>
+body { max-width: 50em; margin: 2em auto; padding: 0 1em; font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; line-height: 1.5; color: #1f2328; }
+section.conversation { margin-bottom: 3em; }
+div.message { margin: 1em 0; padding: 0.5em 1em; border-radius: 6px; border: 1px solid #d0d7de; }
+div.message.user { background: #f6f8fa; }
+div.message.system { background: #fff8c5; }
+div.role { font-weight: bold; text-transform: capitalize; margin-bottom: 0.5em; }
+p.text { white-space: pre-wrap; margin: 0.5em 0; }
+pre { padding: 0.75em; border-radius: 6px; overflow-x: auto; }
+</style>
+</head>
+<body>
+{{- range .Conversations }}
+<section class="conversation">
+<h1>{{ .Title }}</h1>
+{{- range .Messages }}
+<div class="message {{ .Role }}">
+<div class="role">{{ .Role }}</div>
+{{ .Body }}
+</div>
+{{- end }}
+</section>
+{{- end }}
+</body>
+</html>
+`))
+
+type htmlMessage struct {
+	Role string
+	Body template.HTML
+}
+
+type htmlConversation struct {
+	Title    string
+	Messages []htmlMessage
+}
+
+func exportHTML(w io.Writer, conversations []ExportConversation) error {
+	data := struct {
+		Title         string
+		Conversations []htmlConversation
+	}{}
+
+	for _, conversation := range conversations {
+		c := htmlConversation{Title: conversation.Title}
+		for _, message := range conversation.Messages {
+			body, err := renderHTMLMessage(message.Content)
+			if err != nil {
+				return err
+			}
+			c.Messages = append(c.Messages, htmlMessage{Role: message.Role, Body: body})
+		}
+		data.Conversations = append(data.Conversations, c)
+	}
+
+	if len(conversations) == 1 {
+		data.Title = conversations[0].Title
+	} else {
+		data.Title = "oax chat logs"
+	}
+
+	return exportHTMLTemplate.Execute(w, data)
+}
+
+func renderHTMLMessage(content string) (template.HTML, error) {
+	var buf bytes.Buffer
+
+	formatter := chromahtml.New(chromahtml.WithClasses(false), chromahtml.TabWidth(4))
+	style := styles.Get("github")
+
+	for _, block := range SplitFencedCodeBlocks(content) {
+		if !block.Code {
+			text := strings.Trim(block.Text, "\n")
+			if text == "" {
+				continue
+			}
+			buf.WriteString(`<p class="text">`)
+			buf.WriteString(template.HTMLEscapeString(text))
+			buf.WriteString("</p>\n")
+
+			continue
+		}
+
+		iterator, err := codeLexer(block).Tokenise(nil, block.Text+"\n")
+		if err != nil {
+			return "", err
+		}
+		if err := formatter.Format(&buf, style, iterator); err != nil {
+			return "", err
+		}
+	}
+
+	return template.HTML(buf.String()), nil
+}
+
+func codeLexer(block MarkdownBlock) chroma.Lexer {
+	lexer := lexers.Get(block.Language)
+	if lexer == nil {
+		lexer = lexers.Analyse(block.Text)
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+
+	return chroma.Coalesce(lexer)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
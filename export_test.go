@@ -0,0 +1,161 @@
+package oax
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewExportConversation(t *testing.T) {
+	filePath := filepath.Join("logs", "2023-05-01_review.toml")
+	chatLog := &ChatLog{
+		FilePath: &filePath,
+		ChatLogToml: ChatLogToml{
+			Model: "gpt-4",
+			Messages: []ChatMessage{
+				{Role: "system", Content: "You review code."},
+				{Role: "user", Content: "Review this."},
+				{Role: "assistant", Content: "Looks good."},
+			},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		roles    []string
+		expected []ChatMessage
+	}{
+		{
+			"all roles",
+			nil,
+			chatLog.ChatLogToml.Messages,
+		},
+		{
+			"user and assistant",
+			[]string{"user", "assistant"},
+			[]ChatMessage{
+				{Role: "user", Content: "Review this."},
+				{Role: "assistant", Content: "Looks good."},
+			},
+		},
+		{
+			"unused role",
+			[]string{"tool"},
+			[]ChatMessage{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := NewExportConversation(chatLog, tc.roles)
+			if result.Title != "2023-05-01_review" {
+				t.Errorf("Expected %q but got %q", "2023-05-01_review", result.Title)
+			}
+			if !reflect.DeepEqual(result.Messages, tc.expected) {
+				t.Errorf("Expected %+v but got %+v", tc.expected, result.Messages)
+			}
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	conversations := []ExportConversation{
+		{
+			Title:    "first",
+			FilePath: "first.toml",
+			Model:    "gpt-4",
+			Messages: []ChatMessage{
+				{Role: "user", Content: "Say <hi>"},
+				{Role: "assistant", Content: "Here:\n```go\nfmt.Println(\"hi\")\n```"},
+			},
+		},
+		{
+			Title:    "empty",
+			FilePath: "empty.toml",
+			Messages: []ChatMessage{},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		format   string
+		contains []string
+		expected string
+	}{
+		{
+			name:   "markdown",
+			format: ExportFormatMarkdown,
+			expected: "# first\n\n### user\n\nSay <hi>\n\n### assistant\n\nHere:\n```go\nfmt.Println(\"hi\")\n```\n" +
+				"\n---\n\n# empty\n",
+		},
+		{
+			name:   "json",
+			format: ExportFormatJSON,
+			expected: `[
+  {
+    "title": "first",
+    "file": "first.toml",
+    "model": "gpt-4",
+    "messages": [
+      {
+        "role": "user",
+        "content": "Say <hi>"
+      },
+      {
+        "role": "assistant",
+        "content": "Here:\n` + "```go" + `\nfmt.Println(\"hi\")\n` + "```" + `"
+      }
+    ]
+  },
+  {
+    "title": "empty",
+    "file": "empty.toml",
+    "messages": []
+  }
+]
+`,
+		},
+		{
+			name:     "finetune",
+			format:   ExportFormatFinetune,
+			expected: `{"messages":[{"role":"user","content":"Say <hi>"},{"role":"assistant","content":"Here:\n` + "```go" + `\nfmt.Println(\"hi\")\n` + "```" + `"}]}` + "\n",
+		},
+		{
+			name:   "html",
+			format: ExportFormatHTML,
+			contains: []string{
+				"<title>oax chat logs</title>",
+				"<h1>first</h1>",
+				`<div class="message user">`,
+				`<p class="text">Say &lt;hi&gt;</p>`,
+				"<pre",
+				"<h1>empty</h1>",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Export(&buf, conversations, tc.format); err != nil {
+				t.Fatalf("Error: Return err func: %v", err)
+			}
+
+			result := buf.String()
+			if tc.contains == nil && result != tc.expected {
+				t.Errorf("Expected %q but got %q", tc.expected, result)
+			}
+			for _, s := range tc.contains {
+				if !strings.Contains(result, s) {
+					t.Errorf("Expected %q to contain %q", result, s)
+				}
+			}
+		})
+	}
+
+	if err := Export(&bytes.Buffer{}, conversations, "pdf"); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,178 @@
+package oax
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var (
+	ErrorChatLogLocked = errors.New("chat log is in use by another oax process")
+)
+
+const (
+	backupFileSuffix = ".bak"
+	lockFileSuffix   = ".lock"
+	// headerFileSuffix names the file holding the header fields of a chat
+	// log whose format keeps them out of the chat log.
+	headerFileSuffix = ".header.json"
+)
+
+// writeFileAtomic writes data to a temporary file in the same directory,
+// syncs it and renames it over filePath, so readers never see a partially
+// written file. When keepBackup is true the previous content is kept as
+// filePath + ".bak".
+func writeFileAtomic(filePath string, data []byte, perm os.FileMode, keepBackup bool) error {
+	dir := filepath.Dir(filePath)
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(filePath)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	cleanup := func() {
+		tmp.Close()
+		os.Remove(tmpPath)
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		cleanup()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		cleanup()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if keepBackup {
+		if err := backupFile(filePath); err != nil {
+			os.Remove(tmpPath)
+			return err
+		}
+	}
+
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+func backupFile(filePath string) error {
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filePath+backupFileSuffix, data, info.Mode().Perm(), false)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	// Some platforms and filesystems do not support fsync on directories,
+	// and the rename has already happened at this point, so the error is
+	// not fatal.
+	d.Sync()
+
+	return nil
+}
+
+type fileLock struct {
+	file *os.File
+	path string
+}
+
+// lockFile takes an exclusive advisory lock on filePath + ".lock" without
+// blocking. It returns ErrorChatLogLocked when another process holds it.
+func lockFile(filePath string) (*fileLock, error) {
+	lockPath := filePath + lockFileSuffix
+
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0644)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := flock(f); err != nil {
+			f.Close()
+			if errors.Is(err, ErrorChatLogLocked) {
+				return nil, fmt.Errorf("%s: %w", filePath, err)
+			}
+
+			return nil, err
+		}
+
+		// The previous holder removes the lock file on unlock, possibly
+		// after it was opened here. A lock on a removed file excludes
+		// nobody, so open the lock file again.
+		inPlace, err := isLockFileInPlace(f, lockPath)
+		if err != nil {
+			funlock(f)
+			f.Close()
+
+			return nil, err
+		}
+		if inPlace {
+			return &fileLock{file: f, path: lockPath}, nil
+		}
+
+		funlock(f)
+		f.Close()
+	}
+}
+
+// isLockFileInPlace reports whether f is still the file at lockPath.
+func isLockFileInPlace(f *os.File, lockPath string) (bool, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	pathInfo, err := os.Stat(lockPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return os.SameFile(info, pathInfo), nil
+}
+
+func (l *fileLock) unlock() error {
+	// The lock file is removed while the lock is still held, so that
+	// lockFile can detect a lock taken on the removed file. Windows does
+	// not remove an open file, and the lock file is left in place there.
+	os.Remove(l.path)
+
+	if err := funlock(l.file); err != nil {
+		l.file.Close()
+		return err
+	}
+
+	return l.file.Close()
+}
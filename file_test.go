@@ -0,0 +1,104 @@
+package oax
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "log.toml")
+
+	if err := writeFileAtomic(filePath, []byte("first"), 0644, true); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if _, err := os.Stat(filePath + backupFileSuffix); !os.IsNotExist(err) {
+		t.Errorf("Expected no backup file on first write but got %v", err)
+	}
+
+	if err := writeFileAtomic(filePath, []byte("second"), 0644, true); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+
+	testCases := []struct {
+		path     string
+		expected string
+	}{
+		{filePath, "second"},
+		{filePath + backupFileSuffix, "first"},
+	}
+
+	for _, tc := range testCases {
+		data, err := os.ReadFile(tc.path)
+		if err != nil {
+			t.Fatalf("Error: Cannot read file: %v", err)
+		}
+		if string(data) != tc.expected {
+			t.Errorf("Expected %q but got %q", tc.expected, string(data))
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(filePath))
+	if err != nil {
+		t.Fatalf("Error: Cannot read dir: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected 2 files but got %d", len(entries))
+	}
+}
+
+func TestChatLogLock(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "log.toml")
+
+	first := ChatLog{FilePath: &filePath}
+	if err := first.Lock(); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+
+	second := ChatLog{FilePath: &filePath}
+	if err := second.Lock(); !errors.Is(err, ErrorChatLogLocked) {
+		t.Errorf("Expected %v but got %v", ErrorChatLogLocked, err)
+	}
+
+	if err := first.Unlock(); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if err := second.Lock(); err != nil {
+		t.Errorf("Expected lock after unlock but got %v", err)
+	}
+	second.Unlock()
+}
+
+func TestIsLockFileInPlace(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "log.toml"+lockFileSuffix)
+
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatalf("Error: Cannot create file: %v", err)
+	}
+	defer f.Close()
+
+	inPlace, err := isLockFileInPlace(f, lockPath)
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if !inPlace {
+		t.Errorf("Expected the opened lock file to be in place")
+	}
+
+	if err := os.Remove(lockPath); err != nil {
+		t.Skipf("open files cannot be removed: %v", err)
+	}
+	if err := os.WriteFile(lockPath, nil, 0644); err != nil {
+		t.Fatalf("Error: Cannot write file: %v", err)
+	}
+
+	inPlace, err = isLockFileInPlace(f, lockPath)
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if inPlace {
+		t.Errorf("Expected a replaced lock file not to be in place")
+	}
+}
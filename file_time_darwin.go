@@ -0,0 +1,19 @@
+//go:build darwin
+
+package oax
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// fileCreateTime returns the birth time of the file.
+func fileCreateTime(path string, info os.FileInfo) time.Time {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return info.ModTime()
+	}
+
+	return time.Unix(stat.Birthtimespec.Unix())
+}
@@ -0,0 +1,22 @@
+//go:build linux
+
+package oax
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// fileCreateTime returns the birth time of the file when the filesystem
+// records it, and the modification time otherwise.
+func fileCreateTime(path string, info os.FileInfo) time.Time {
+	var stat unix.Statx_t
+	err := unix.Statx(unix.AT_FDCWD, path, unix.AT_SYMLINK_NOFOLLOW, unix.STATX_BTIME, &stat)
+	if err != nil || stat.Mask&unix.STATX_BTIME == 0 {
+		return info.ModTime()
+	}
+
+	return time.Unix(stat.Btime.Sec, int64(stat.Btime.Nsec))
+}
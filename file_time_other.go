@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !windows
+
+package oax
+
+import (
+	"os"
+	"time"
+)
+
+// fileCreateTime falls back to the modification time on platforms where
+// the creation time is not available.
+func fileCreateTime(path string, info os.FileInfo) time.Time {
+	return info.ModTime()
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package oax
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// fileCreateTime returns the creation time of the file.
+func fileCreateTime(path string, info os.FileInfo) time.Time {
+	data, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return info.ModTime()
+	}
+
+	return time.Unix(0, data.CreationTime.Nanoseconds())
+}
@@ -3,22 +3,25 @@ module github.com/shuntaka9576/oax
 go 1.20
 
 require (
+	github.com/alecthomas/chroma/v2 v2.7.0
 	github.com/alecthomas/kong v0.7.1
+	github.com/gdamore/tcell/v2 v2.5.3
 	github.com/itchyny/timefmt-go v0.1.5
 	github.com/ktr0731/go-fuzzyfinder v0.7.0
+	github.com/mattn/go-runewidth v0.0.14
 	github.com/pelletier/go-toml v1.9.5
+	golang.org/x/crypto v0.0.0-20220525230936-793ad666bf5e
+	golang.org/x/sys v0.1.0
+	golang.org/x/term v0.1.0
 )
 
 require (
+	github.com/dlclark/regexp2 v1.4.0 // indirect
 	github.com/gdamore/encoding v1.0.0 // indirect
-	github.com/gdamore/tcell/v2 v2.5.3 // indirect
 	github.com/ktr0731/go-ansisgr v0.1.0 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
-	github.com/mattn/go-runewidth v0.0.14 // indirect
 	github.com/nsf/termbox-go v1.1.1 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/rivo/uniseg v0.4.2 // indirect
-	golang.org/x/sys v0.1.0 // indirect
-	golang.org/x/term v0.1.0 // indirect
 	golang.org/x/text v0.4.0 // indirect
 )
@@ -0,0 +1,285 @@
+package oax
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	ImportFormatChatGPT = "chatgpt"
+	ImportFormatJSONL   = "jsonl"
+)
+
+type ImportedConversation struct {
+	// Source identifies the conversation, as "<format>:<id>". Without an
+	// id in the import, the id is a hash of the messages.
+	Source     string
+	Title      string
+	CreateTime time.Time
+	UpdateTime time.Time
+	Messages   []ChatMessage
+}
+
+// ParseImport reads conversations in the given format. An empty format is
+// detected from the content: a JSON array is a ChatGPT export, anything
+// else is read as JSONL.
+func ParseImport(r io.Reader, format string) ([]ImportedConversation, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == "" {
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+			format = ImportFormatChatGPT
+		} else {
+			format = ImportFormatJSONL
+		}
+	}
+
+	switch format {
+	case ImportFormatChatGPT:
+		return ParseChatGPTExport(data)
+	case ImportFormatJSONL:
+		return ParseJSONLConversations(data)
+	default:
+		return nil, fmt.Errorf("unknown import format %q", format)
+	}
+}
+
+type chatGPTConversation struct {
+	ID          string                 `json:"id"`
+	Title       string                 `json:"title"`
+	CreateTime  float64                `json:"create_time"`
+	UpdateTime  float64                `json:"update_time"`
+	CurrentNode string                 `json:"current_node"`
+	Mapping     map[string]chatGPTNode `json:"mapping"`
+}
+
+type chatGPTNode struct {
+	ID       string          `json:"id"`
+	Message  *chatGPTMessage `json:"message"`
+	Parent   *string         `json:"parent"`
+	Children []string        `json:"children"`
+}
+
+type chatGPTMessage struct {
+	Author struct {
+		Role string `json:"role"`
+	} `json:"author"`
+	Content struct {
+		ContentType string            `json:"content_type"`
+		Parts       []json.RawMessage `json:"parts"`
+		Text        string            `json:"text"`
+	} `json:"content"`
+	Metadata struct {
+		IsVisuallyHiddenFromConversation bool `json:"is_visually_hidden_from_conversation"`
+	} `json:"metadata"`
+}
+
+// ParseChatGPTExport reads conversations.json from a chat.openai.com data
+// export. Conversations are stored as a tree of edits and regenerations;
+// only the branch ending at current_node is imported.
+func ParseChatGPTExport(data []byte) ([]ImportedConversation, error) {
+	var exported []chatGPTConversation
+	if err := json.Unmarshal(data, &exported); err != nil {
+		return nil, fmt.Errorf("parse ChatGPT export: %w", err)
+	}
+
+	var conversations []ImportedConversation
+	for _, c := range exported {
+		conversation := ImportedConversation{
+			Title:      c.Title,
+			CreateTime: unixFloatToTime(c.CreateTime),
+			UpdateTime: unixFloatToTime(c.UpdateTime),
+		}
+
+		branch, err := chatGPTCurrentBranch(c)
+		if err != nil {
+			return nil, fmt.Errorf("parse ChatGPT export: conversation %q: %w", c.Title, err)
+		}
+
+		for _, node := range branch {
+			if message, ok := node.Message.chatMessage(); ok {
+				conversation.Messages = append(conversation.Messages, message)
+			}
+		}
+		conversation.Source = importSource(ImportFormatChatGPT, c.ID, conversation.Messages)
+
+		if len(conversation.Messages) > 0 {
+			conversations = append(conversations, conversation)
+		}
+	}
+
+	return conversations, nil
+}
+
+func chatGPTCurrentBranch(c chatGPTConversation) ([]chatGPTNode, error) {
+	current := c.CurrentNode
+	if current == "" {
+		leaf, err := chatGPTLatestLeaf(c)
+		if err != nil {
+			return nil, err
+		}
+		current = leaf
+	}
+
+	var branch []chatGPTNode
+	seen := map[string]bool{}
+	for current != "" && !seen[current] {
+		node, ok := c.Mapping[current]
+		if !ok {
+			break
+		}
+		seen[current] = true
+		branch = append(branch, node)
+
+		if node.Parent == nil {
+			break
+		}
+		current = *node.Parent
+	}
+
+	for i, j := 0, len(branch)-1; i < j; i, j = i+1, j-1 {
+		branch[i], branch[j] = branch[j], branch[i]
+	}
+
+	return branch, nil
+}
+
+// chatGPTLatestLeaf is used for old exports without current_node; it
+// follows the last child from the root.
+func chatGPTLatestLeaf(c chatGPTConversation) (string, error) {
+	var ids []string
+	for id, node := range c.Mapping {
+		if node.Parent == nil {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return "", nil
+	}
+	sort.Strings(ids)
+
+	current := ids[0]
+	seen := map[string]bool{}
+	for {
+		if seen[current] {
+			return "", fmt.Errorf("cycle in children at node %s", current)
+		}
+		seen[current] = true
+
+		node := c.Mapping[current]
+		if len(node.Children) == 0 {
+			return current, nil
+		}
+		current = node.Children[len(node.Children)-1]
+	}
+}
+
+func (m *chatGPTMessage) chatMessage() (ChatMessage, bool) {
+	if m == nil || m.Metadata.IsVisuallyHiddenFromConversation {
+		return ChatMessage{}, false
+	}
+
+	role := m.Author.Role
+	if role != "system" && role != "user" && role != "assistant" {
+		return ChatMessage{}, false
+	}
+
+	var parts []string
+	for _, raw := range m.Content.Parts {
+		var part string
+		if err := json.Unmarshal(raw, &part); err == nil && part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 && m.Content.Text != "" {
+		parts = append(parts, m.Content.Text)
+	}
+
+	content := trimTrailingSpace(strings.Join(parts, "\n"))
+	if content == "" {
+		return ChatMessage{}, false
+	}
+
+	return ChatMessage{Role: role, Content: content}, true
+}
+
+type jsonlConversation struct {
+	ID         string        `json:"id"`
+	Title      string        `json:"title"`
+	CreateTime float64       `json:"create_time"`
+	UpdateTime float64       `json:"update_time"`
+	Messages   []ChatMessage `json:"messages"`
+}
+
+// ParseJSONLConversations reads one {"messages": [...]} object per line,
+// the layout written by `oax export -F finetune`. Optional "title",
+// "create_time" and "update_time" (Unix seconds) fields are used for the
+// log file name, and an optional "id" to recognise the conversation when it
+// is imported again.
+func ParseJSONLConversations(data []byte) ([]ImportedConversation, error) {
+	var conversations []ImportedConversation
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), len(data)+1)
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var c jsonlConversation
+		if err := json.Unmarshal(line, &c); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		if len(c.Messages) == 0 {
+			continue
+		}
+
+		conversations = append(conversations, ImportedConversation{
+			Source:     importSource(ImportFormatJSONL, c.ID, c.Messages),
+			Title:      c.Title,
+			CreateTime: unixFloatToTime(c.CreateTime),
+			UpdateTime: unixFloatToTime(c.UpdateTime),
+			Messages:   c.Messages,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return conversations, nil
+}
+
+func importSource(format string, id string, messages []ChatMessage) string {
+	if id == "" {
+		data, _ := json.Marshal(messages)
+		sum := sha256.Sum256(data)
+		id = hex.EncodeToString(sum[:8])
+	}
+
+	return format + ":" + id
+}
+
+func unixFloatToTime(sec float64) time.Time {
+	if sec <= 0 {
+		return time.Time{}
+	}
+	whole, frac := math.Modf(sec)
+
+	return time.Unix(int64(whole), int64(frac*1e9))
+}
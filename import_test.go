@@ -0,0 +1,97 @@
+package oax
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseChatGPTExport(t *testing.T) {
+	input := `[{
+  "id": "c-1",
+  "title": "gRPC deadlines",
+  "create_time": 1680000000.5,
+  "update_time": 1680000100,
+  "current_node": "a2",
+  "mapping": {
+    "root": {"id": "root", "message": null, "parent": null, "children": ["s"]},
+    "s": {"id": "s", "message": {"author": {"role": "system"}, "content": {"content_type": "text", "parts": [""]}}, "parent": "root", "children": ["u"]},
+    "u": {"id": "u", "message": {"author": {"role": "user"}, "content": {"content_type": "text", "parts": ["How do deadlines work?"]}}, "parent": "s", "children": ["a1", "a2"]},
+    "a1": {"id": "a1", "message": {"author": {"role": "assistant"}, "content": {"content_type": "text", "parts": ["regenerated away"]}}, "parent": "u", "children": []},
+    "a2": {"id": "a2", "message": {"author": {"role": "assistant"}, "content": {"content_type": "text", "parts": ["They propagate.", {"asset": "image"}]}}, "parent": "u", "children": []}
+  }
+}]`
+
+	conversations, err := ParseImport(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if len(conversations) != 1 {
+		t.Fatalf("Expected 1 conversation but got %d", len(conversations))
+	}
+
+	conversation := conversations[0]
+	expected := []ChatMessage{
+		{Role: "user", Content: "How do deadlines work?"},
+		{Role: "assistant", Content: "They propagate."},
+	}
+	if !reflect.DeepEqual(conversation.Messages, expected) {
+		t.Errorf("Expected %q but got %q", expected, conversation.Messages)
+	}
+	if conversation.Source != "chatgpt:c-1" {
+		t.Errorf("Expected %q but got %q", "chatgpt:c-1", conversation.Source)
+	}
+	if conversation.Title != "gRPC deadlines" {
+		t.Errorf("Expected %q but got %q", "gRPC deadlines", conversation.Title)
+	}
+	if !conversation.CreateTime.Equal(time.Unix(1680000000, 5e8)) {
+		t.Errorf("Expected create time %v but got %v", time.Unix(1680000000, 5e8), conversation.CreateTime)
+	}
+}
+
+func TestParseChatGPTExportChildCycle(t *testing.T) {
+	input := `[{
+  "title": "broken",
+  "mapping": {
+    "root": {"id": "root", "message": null, "parent": null, "children": ["u"]},
+    "u": {"id": "u", "message": {"author": {"role": "user"}, "content": {"content_type": "text", "parts": ["hi"]}}, "parent": "root", "children": ["root"]}
+  }
+}]`
+
+	_, err := ParseImport(strings.NewReader(input), "")
+	if err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("Expected a cycle error but got %v", err)
+	}
+}
+
+func TestParseJSONLConversations(t *testing.T) {
+	input := `{"id": "a-1", "title": "a", "messages": [{"role": "user", "content": "hi"}]}
+
+{"messages": [{"role": "user", "content": "hello"}, {"role": "assistant", "content": "hey"}]}
+`
+
+	conversations, err := ParseImport(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if len(conversations) != 2 {
+		t.Fatalf("Expected 2 conversations but got %d", len(conversations))
+	}
+	if len(conversations[1].Messages) != 2 {
+		t.Errorf("Expected 2 messages but got %d", len(conversations[1].Messages))
+	}
+	if conversations[0].Source != "jsonl:a-1" {
+		t.Errorf("Expected %q but got %q", "jsonl:a-1", conversations[0].Source)
+	}
+
+	// Without an id, the source is derived from the messages so that it is
+	// the same when imported again.
+	again, err := ParseImport(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if !strings.HasPrefix(conversations[1].Source, "jsonl:") || again[1].Source != conversations[1].Source {
+		t.Errorf("Expected a stable source but got %q and %q", conversations[1].Source, again[1].Source)
+	}
+}
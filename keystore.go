@@ -0,0 +1,248 @@
+package oax
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+)
+
+const (
+	KeyStoreFileName = "keys.enc"
+
+	// EnvKeyStorePassphrase is read instead of prompting for the key store
+	// passphrase.
+	EnvKeyStorePassphrase = "OAX_KEYSTORE_PASSPHRASE"
+
+	keyStoreVersion = 1
+)
+
+// stdinReader is shared so that reading several secrets from a pipe does
+// not lose buffered input.
+var stdinReader = bufio.NewReader(os.Stdin)
+
+var ErrorKeyStoreDecrypt = errors.New("cannot decrypt the key store: wrong passphrase or corrupted file")
+
+// KeyStore is a local file holding API keys by profile name, encrypted
+// with AES-GCM using a key derived from a passphrase with scrypt.
+type KeyStore struct {
+	FilePath   string
+	passphrase []byte
+	keys       map[string]string
+}
+
+type keyStoreFile struct {
+	Version    int    `json:"version"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func NewKeyStore(configDir string) *KeyStore {
+	return &KeyStore{FilePath: filepath.Join(configDir, KeyStoreFileName)}
+}
+
+// SetPassphrase sets the passphrase instead of reading it from
+// OAX_KEYSTORE_PASSPHRASE or the terminal.
+func (k *KeyStore) SetPassphrase(passphrase string) {
+	k.passphrase = []byte(passphrase)
+}
+
+func (k *KeyStore) Get(name string) (string, error) {
+	err := k.load()
+	if err != nil {
+		return "", err
+	}
+
+	key, ok := k.keys[name]
+	if !ok {
+		return "", fmt.Errorf("no API key for profile %s in the key store %s", name, k.FilePath)
+	}
+
+	return key, nil
+}
+
+func (k *KeyStore) Set(name string, apiKey string) error {
+	err := k.load()
+	if err != nil {
+		return err
+	}
+
+	k.keys[name] = apiKey
+
+	return k.save()
+}
+
+func (k *KeyStore) Delete(name string) error {
+	err := k.load()
+	if err != nil {
+		return err
+	}
+
+	delete(k.keys, name)
+
+	return k.save()
+}
+
+func (k *KeyStore) load() error {
+	if k.keys != nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(k.FilePath)
+	if os.IsNotExist(err) {
+		k.keys = map[string]string{}
+
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var file keyStoreFile
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		return fmt.Errorf("invalid key store %s: %w", k.FilePath, err)
+	}
+	if file.Version != keyStoreVersion {
+		return fmt.Errorf("unsupported key store version %d", file.Version)
+	}
+
+	gcm, err := k.cipher(file.Salt)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := gcm.Open(nil, file.Nonce, file.Ciphertext, nil)
+	if err != nil {
+		return ErrorKeyStoreDecrypt
+	}
+
+	keys := map[string]string{}
+	err = json.Unmarshal(plaintext, &keys)
+	if err != nil {
+		return fmt.Errorf("invalid key store %s: %w", k.FilePath, err)
+	}
+	k.keys = keys
+
+	return nil
+}
+
+func (k *KeyStore) save() error {
+	plaintext, err := json.Marshal(k.keys)
+	if err != nil {
+		return err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+
+	gcm, err := k.cipher(salt)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(keyStoreFile{
+		Version:    keyStoreVersion,
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, plaintext, nil),
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(k.FilePath, data, 0600, false)
+}
+
+func (k *KeyStore) cipher(salt []byte) (cipher.AEAD, error) {
+	if k.passphrase == nil {
+		passphrase, err := keyStorePassphrase(k.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		k.passphrase = []byte(passphrase)
+	}
+
+	key, err := scrypt.Key(k.passphrase, salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func keyStorePassphrase(filePath string) (string, error) {
+	if passphrase := os.Getenv(EnvKeyStorePassphrase); passphrase != "" {
+		return passphrase, nil
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("set %s to unlock the key store %s without a terminal", EnvKeyStorePassphrase, filePath)
+	}
+
+	passphrase, err := ReadSecret(fmt.Sprintf("Passphrase for %s: ", filePath))
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", errors.New("empty key store passphrase")
+	}
+
+	if !fileExists(filePath) {
+		confirm, err := ReadSecret("Confirm passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if confirm != passphrase {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+
+	return passphrase, nil
+}
+
+// ReadSecret prompts on stderr and reads a line from the terminal without
+// echoing it, or from stdin when it is not a terminal.
+func ReadSecret(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := stdinReader.ReadString('\n')
+		if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+			return "", err
+		}
+
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	secret, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+
+	return string(secret), nil
+}
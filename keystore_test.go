@@ -0,0 +1,83 @@
+package oax
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestKeyStore(t *testing.T) {
+	configDir := t.TempDir()
+
+	keyStore := NewKeyStore(configDir)
+	keyStore.SetPassphrase("correct horse")
+	if err := keyStore.Set("me", "sk-me"); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if err := keyStore.Set("work", "sk-work"); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if err := keyStore.Delete("work"); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(keyStore.FilePath)
+		if err != nil {
+			t.Fatalf("Error: Return err func: %v", err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("Expected mode 0600 but got %04o", info.Mode().Perm())
+		}
+	}
+
+	reopened := NewKeyStore(configDir)
+	reopened.SetPassphrase("correct horse")
+	apiKey, err := reopened.Get("me")
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if apiKey != "sk-me" {
+		t.Errorf("Expected %q but got %q", "sk-me", apiKey)
+	}
+	if _, err := reopened.Get("work"); err == nil {
+		t.Errorf("Expected error for a deleted key")
+	}
+
+	wrong := NewKeyStore(configDir)
+	wrong.SetPassphrase("battery staple")
+	if _, err := wrong.Get("me"); !errors.Is(err, ErrorKeyStoreDecrypt) {
+		t.Errorf("Expected %v but got %v", ErrorKeyStoreDecrypt, err)
+	}
+}
+
+func TestResolveAPIKey(t *testing.T) {
+	t.Setenv("OAX_TEST_API_KEY", "sk-env")
+
+	testCases := []struct {
+		name     string
+		profile  Profile
+		expected string
+	}{
+		{"apiKey", Profile{Name: "a", ApiKey: "sk-plain", ApiKeyEnv: "OAX_TEST_API_KEY"}, "sk-plain"},
+		{"apiKeyEnv", Profile{Name: "a", ApiKeyEnv: "OAX_TEST_API_KEY"}, "sk-env"},
+		{"apiKeyCommand", Profile{Name: "a", ApiKeyCommand: "echo sk-command"}, "sk-command"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apiKey, _, err := tc.profile.resolveAPIKey(NewKeyStore(t.TempDir()))
+			if err != nil {
+				t.Fatalf("Error: Return err func: %v", err)
+			}
+			if apiKey != tc.expected {
+				t.Errorf("Expected %q but got %q", tc.expected, apiKey)
+			}
+		})
+	}
+
+	if _, _, err := (Profile{Name: "a", ApiKeyEnv: "OAX_TEST_UNSET_API_KEY"}).resolveAPIKey(nil); err == nil {
+		t.Errorf("Expected error for an unset environment variable")
+	}
+}
@@ -0,0 +1,342 @@
+package oax
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+)
+
+const (
+	DefaultModel  = "gpt-3.5-turbo"
+	DefaultEditor = "vim"
+
+	EnvAPIKey    = "OAX_API_KEY"
+	EnvModel     = "OAX_MODEL"
+	EnvProfile   = "OAX_PROFILE"
+	EnvConfigDir = "OAX_CONFIG_DIR"
+
+	EnvXDGConfigHome = "XDG_CONFIG_HOME"
+	EnvXDGDataHome   = "XDG_DATA_HOME"
+
+	SourceDefault = "default"
+)
+
+// ConfigOption holds the command line flags that override configuration
+// values. Configuration is layered as defaults < global settings file <
+// project settings file < OAX_* environment variables < flags.
+type ConfigOption struct {
+	ConfigDir string
+	Profile   string
+	Model     string
+}
+
+type ResolvedValue struct {
+	Key    string
+	Value  string
+	Source string
+}
+
+func defaultSettings() *Settings {
+	return &Settings{
+		Setting: Setting{
+			Editor: DefaultEditor,
+		},
+		Chat: Chat{
+			Model: DefaultModel,
+		},
+		Output: Output{
+			Render: RenderRaw,
+			Theme:  DefaultTheme,
+		},
+	}
+}
+
+// applyOverrides applies environment variables and flags on top of the
+// settings files.
+func (c *Config) applyOverrides(opt *ConfigOption) {
+	if model := os.Getenv(EnvModel); model != "" {
+		c.Settings.Chat.Model = model
+		c.Sources["chat.model"] = envSource(EnvModel)
+	}
+	if profile := os.Getenv(EnvProfile); profile != "" {
+		c.ProfileName = profile
+		c.Sources["profile"] = envSource(EnvProfile)
+	}
+	if apiKey := os.Getenv(EnvAPIKey); apiKey != "" {
+		c.APIKey = apiKey
+		c.Sources["apiKey"] = envSource(EnvAPIKey)
+	}
+
+	if opt == nil {
+		return
+	}
+	if opt.Model != "" {
+		c.Settings.Chat.Model = opt.Model
+		c.Sources["chat.model"] = "flag --model"
+	}
+	if opt.Profile != "" {
+		c.ProfileName = opt.Profile
+		c.Sources["profile"] = "flag --profile"
+	}
+}
+
+// SelectProfile returns the profile named by --profile or OAX_PROFILE, or
+// the default profile. OAX_API_KEY replaces the API key of the profile and
+// can be used without any profile. Other API keys are set by ResolveAPIKey.
+func (c *Config) SelectProfile() (Profile, error) {
+	var useProfile Profile
+
+	if c.ProfileName == "" {
+		profile, err := DefaultProfile(c.Profiles)
+		var multipleDefaults *ErrorMultipleDefaultProfiles
+		if errors.As(err, &multipleDefaults) {
+			return Profile{}, err
+		}
+		useProfile = profile
+	} else {
+		for _, profile := range c.Profiles {
+			if profile.Name == c.ProfileName {
+				useProfile = profile
+			}
+		}
+	}
+
+	if c.APIKey != "" {
+		if useProfile.Name == "" {
+			useProfile.Name = c.ProfileName
+			if useProfile.Name == "" {
+				useProfile.Name = envSource(EnvAPIKey)
+			}
+		}
+		useProfile.ApiKey = c.APIKey
+	}
+
+	if useProfile.Name == "" {
+		return Profile{}, fmt.Errorf("invalid profile %s", c.ProfileName)
+	}
+
+	return useProfile, nil
+}
+
+// ResolveAPIKey sets the API key of profile from apiKey, apiKeyEnv,
+// apiKeyCommand or the key store, unless OAX_API_KEY is set. Only commands
+// that call the API resolve it, since apiKeyCommand runs a command and the
+// key store asks for its passphrase.
+func (c *Config) ResolveAPIKey(profile *Profile) error {
+	if c.APIKey != "" {
+		return nil
+	}
+
+	apiKey, source, err := profile.resolveAPIKey(c.KeyStore())
+	if err != nil {
+		return err
+	}
+
+	profile.ApiKey = apiKey
+	if c.Sources == nil {
+		c.Sources = map[string]string{}
+	}
+	c.Sources["apiKey"] = source
+
+	return nil
+}
+
+// KeyStore returns the encrypted key store in the config directory.
+func (c *Config) KeyStore() *KeyStore {
+	if c.keyStore == nil {
+		c.keyStore = NewKeyStore(c.ConfigDir)
+	}
+
+	return c.keyStore
+}
+
+// Resolved lists every effective configuration value and where it came
+// from. API keys are masked.
+func (c *Config) Resolved() []ResolvedValue {
+	values := []ResolvedValue{
+		{Key: "configDir", Value: c.ConfigDir, Source: c.source("configDir")},
+		{Key: "dataDir", Value: c.DataDir, Source: c.source("dataDir")},
+	}
+
+	model, err := c.ResolveModel()
+	if err != nil {
+		values = append(values, ResolvedValue{Key: "model", Value: c.Settings.Chat.Model, Source: err.Error()})
+	} else if model.Alias != "" {
+		values = append(values, ResolvedValue{Key: "model", Value: model.Model, Source: fmt.Sprintf("model alias %s", model.Alias)})
+	}
+
+	profile, err := c.SelectProfile()
+	if err != nil {
+		values = append(values, ResolvedValue{Key: "profile", Value: c.ProfileName, Source: err.Error()})
+	} else {
+		profileSource := c.source("profile")
+		if c.ProfileName == "" {
+			profileSource = fmt.Sprintf("default profile (%s)", profileFilePath)
+		}
+
+		organizationIDSource := SourceDefault
+		if profile.OrganizationID != "" {
+			organizationIDSource = fmt.Sprintf("profile %s (%s)", profile.Name, profileFilePath)
+		}
+
+		// The API key is not resolved here; see ResolveAPIKey.
+		apiKey, apiKeySource := MaskAPIKey(profile.ApiKey), c.source("apiKey")
+		if c.APIKey == "" {
+			if profile.ApiKey != "" {
+				apiKeySource = fmt.Sprintf("profile %s apiKey (%s)", profile.Name, profileFilePath)
+			} else {
+				apiKey = "(resolved when used)"
+				apiKeySource = fmt.Sprintf("profile %s %s", profile.Name, profile.apiKeyField())
+			}
+		}
+
+		values = append(values,
+			ResolvedValue{Key: "profile", Value: profile.Name, Source: profileSource},
+			ResolvedValue{Key: "apiKey", Value: apiKey, Source: apiKeySource},
+			ResolvedValue{Key: "organizationId", Value: profile.OrganizationID, Source: organizationIDSource},
+		)
+	}
+
+	for _, key := range settingKeys() {
+		values = append(values, ResolvedValue{
+			Key:    key,
+			Value:  settingValue(&c.Settings, key),
+			Source: c.source(key),
+		})
+	}
+
+	return values
+}
+
+func (c *Config) source(key string) string {
+	if source, ok := c.Sources[key]; ok {
+		return source
+	}
+
+	return SourceDefault
+}
+
+// markSources records source for every setting key present in tree.
+func markSources(sources map[string]string, tree *toml.Tree, source string, keys []string) {
+	if tree == nil {
+		return
+	}
+
+	for _, key := range keys {
+		if tree.Has(key) {
+			sources[key] = source
+		}
+	}
+}
+
+// settingKeys lists the dotted keys of Settings, such as "chat.model",
+// from the toml struct tags. Tables keyed by name, such as models, are
+// listed as one key.
+func settingKeys() []string {
+	var keys []string
+
+	settingsType := reflect.TypeOf(Settings{})
+	for i := 0; i < settingsType.NumField(); i++ {
+		section := settingsType.Field(i)
+		sectionName := tomlFieldName(section)
+
+		if section.Type.Kind() != reflect.Struct {
+			keys = append(keys, sectionName)
+
+			continue
+		}
+
+		for j := 0; j < section.Type.NumField(); j++ {
+			keys = append(keys, sectionName+"."+tomlFieldName(section.Type.Field(j)))
+		}
+	}
+
+	return keys
+}
+
+// settingValue formats the value of a key returned by settingKeys.
+func settingValue(settings *Settings, key string) string {
+	value := reflect.ValueOf(settings).Elem()
+	for _, name := range strings.Split(key, ".") {
+		found := false
+		for i := 0; i < value.NumField(); i++ {
+			if tomlFieldName(value.Type().Field(i)) == name {
+				value = value.Field(i)
+				found = true
+
+				break
+			}
+		}
+		if !found {
+			return ""
+		}
+	}
+
+	return formatSettingValue(value)
+}
+
+func formatSettingValue(value reflect.Value) string {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			return ""
+		}
+
+		return formatSettingValue(value.Elem())
+	case reflect.Slice:
+		var items []string
+		for i := 0; i < value.Len(); i++ {
+			item := value.Index(i)
+			// Tables such as templates are listed by name.
+			if item.Kind() == reflect.Struct {
+				if name := item.FieldByName("Name"); name.IsValid() {
+					items = append(items, name.String())
+
+					continue
+				}
+			}
+			items = append(items, formatSettingValue(item))
+		}
+
+		return strings.Join(items, ", ")
+	case reflect.Map:
+		// Tables keyed by name are listed by name.
+		var names []string
+		for _, name := range value.MapKeys() {
+			names = append(names, name.String())
+		}
+		sort.Strings(names)
+
+		return strings.Join(names, ", ")
+	default:
+		return fmt.Sprint(value.Interface())
+	}
+}
+
+func tomlFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("toml"), ",")
+	if name == "" {
+		return field.Name
+	}
+
+	return name
+}
+
+func envSource(name string) string {
+	return "env " + name
+}
+
+// MaskAPIKey hides all but the first and last few characters of an API
+// key.
+func MaskAPIKey(apiKey string) string {
+	if len(apiKey) <= 10 {
+		return strings.Repeat("*", len(apiKey))
+	}
+
+	return apiKey[:3] + strings.Repeat("*", 8) + apiKey[len(apiKey)-4:]
+}
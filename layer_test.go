@@ -0,0 +1,123 @@
+package oax
+
+import (
+	"testing"
+
+	"github.com/pelletier/go-toml"
+)
+
+func TestConfigSources(t *testing.T) {
+	globalTree, err := toml.Load(`[setting]
+  editor = "nvim"
+
+[chat]
+  model = "gpt-4"
+`)
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	projectTree, err := toml.Load(`[chat]
+  model = "gpt-4-32k"
+`)
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+
+	config := &Config{Settings: *defaultSettings(), Sources: map[string]string{}}
+	markSources(config.Sources, globalTree, "global", settingKeys())
+	markSources(config.Sources, projectTree, "project", projectSettingKeys)
+
+	t.Setenv(EnvModel, "")
+	t.Setenv(EnvProfile, "work")
+	t.Setenv(EnvAPIKey, "")
+	config.applyOverrides(&ConfigOption{Model: "gpt-3.5-turbo-0301"})
+
+	testCases := []struct {
+		key            string
+		expectedSource string
+	}{
+		{"setting.editor", "global"},
+		{"chat.model", "flag --model"},
+		{"chat.fileNameFormat", SourceDefault},
+		{"profile", "env " + EnvProfile},
+	}
+
+	for _, tc := range testCases {
+		if source := config.source(tc.key); source != tc.expectedSource {
+			t.Errorf("Expected %q but got %q for %s", tc.expectedSource, source, tc.key)
+		}
+	}
+
+	if model := settingValue(&config.Settings, "chat.model"); model != "gpt-3.5-turbo-0301" {
+		t.Errorf("Expected %q but got %q", "gpt-3.5-turbo-0301", model)
+	}
+	if config.ProfileName != "work" {
+		t.Errorf("Expected %q but got %q", "work", config.ProfileName)
+	}
+}
+
+func TestSelectProfileDoesNotResolveAPIKey(t *testing.T) {
+	config := &Config{
+		Profiles: []Profile{{Name: "command", ApiKeyCommand: "exit 1", Default: true}},
+	}
+
+	profile, err := config.SelectProfile()
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if profile.ApiKey != "" {
+		t.Errorf("Expected no API key before ResolveAPIKey but got %q", profile.ApiKey)
+	}
+
+	if err := config.ResolveAPIKey(&profile); err == nil {
+		t.Errorf("Expected an error from the failing apiKeyCommand")
+	}
+}
+
+func TestSelectProfile(t *testing.T) {
+	profiles := []Profile{
+		{Name: "me", ApiKey: "sk-me", Default: true},
+		{Name: "work", ApiKey: "sk-work"},
+		{Name: "command", ApiKeyCommand: "echo sk-command"},
+	}
+
+	testCases := []struct {
+		name           string
+		profileName    string
+		apiKey         string
+		expectedName   string
+		expectedAPIKey string
+		expectedErr    bool
+	}{
+		{"default", "", "", "me", "sk-me", false},
+		{"named", "work", "", "work", "sk-work", false},
+		{"unknown", "none", "", "", "", true},
+		{"env api key", "work", "sk-env", "work", "sk-env", false},
+		{"env api key without profile", "", "sk-env", "me", "sk-env", false},
+		{"command", "command", "", "command", "sk-command", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &Config{Profiles: profiles, ProfileName: tc.profileName, APIKey: tc.apiKey}
+
+			profile, err := config.SelectProfile()
+			if err == nil {
+				err = config.ResolveAPIKey(&profile)
+			}
+			if tc.expectedErr {
+				if err == nil {
+					t.Fatalf("Expected error but got %+v", profile)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("Error: Return err func: %v", err)
+			}
+			if profile.Name != tc.expectedName || profile.ApiKey != tc.expectedAPIKey {
+				t.Errorf("Expected %s/%s but got %s/%s", tc.expectedName, tc.expectedAPIKey, profile.Name, profile.ApiKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package oax
+
+import (
+	"strings"
+)
+
+// MarkdownBlock is either a fenced code block or the prose between them.
+type MarkdownBlock struct {
+	Code     bool
+	Language string
+	Text     string
+}
+
+// SplitFencedCodeBlocks splits Markdown content at ``` and ~~~ fences. An
+// unterminated fence runs to the end of the content, as it does while an
+// answer is still streaming.
+func SplitFencedCodeBlocks(content string) []MarkdownBlock {
+	var blocks []MarkdownBlock
+	var lines []string
+	var fence string
+	var language string
+
+	flush := func(code bool) {
+		if len(lines) == 0 && !code {
+			return
+		}
+		blocks = append(blocks, MarkdownBlock{
+			Code:     code,
+			Language: language,
+			Text:     strings.Join(lines, "\n"),
+		})
+		lines = nil
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+
+		if fence == "" {
+			if marker := fenceMarker(trimmed); marker != "" {
+				flush(false)
+				fence = marker
+				language = strings.TrimSpace(strings.TrimLeft(trimmed, marker[:1]))
+				if i := strings.IndexAny(language, " {"); i >= 0 {
+					language = language[:i]
+				}
+
+				continue
+			}
+		} else if strings.HasPrefix(trimmed, fence) && strings.TrimLeft(trimmed, fence[:1]) == "" {
+			flush(true)
+			fence = ""
+			language = ""
+
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	flush(fence != "")
+
+	return blocks
+}
+
+func fenceMarker(line string) string {
+	for _, c := range []byte{'`', '~'} {
+		n := 0
+		for n < len(line) && line[n] == c {
+			n++
+		}
+		if n >= 3 {
+			if c == '`' && strings.Contains(line[n:], "`") {
+				return ""
+			}
+
+			return line[:n]
+		}
+	}
+
+	return ""
+}
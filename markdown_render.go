@@ -0,0 +1,473 @@
+package oax
+
+import (
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/mattn/go-runewidth"
+)
+
+const (
+	// RenderMarkdown formats answers as Markdown on the terminal.
+	RenderMarkdown = "markdown"
+	// RenderRaw writes answers as they are received.
+	RenderRaw = "raw"
+	// DefaultTheme is the chroma style of highlighted code blocks.
+	DefaultTheme = "monokai"
+)
+
+const (
+	ansiReset      = "\x1b[0m"
+	ansiBold       = "\x1b[1m"
+	ansiDim        = "\x1b[2m"
+	ansiItalic     = "\x1b[3m"
+	ansiHeading    = "\x1b[1;35m"
+	ansiInlineCode = "\x1b[36m"
+	ansiMarker     = "\x1b[33m"
+
+	markdownRuleWidth = 40
+)
+
+var (
+	markdownHeading   = regexp.MustCompile(`^(#{1,6}) `)
+	markdownBullet    = regexp.MustCompile(`^([-*+]) `)
+	markdownOrdered   = regexp.MustCompile(`^(\d+[.)]) `)
+	markdownRule      = regexp.MustCompile(`^([-*_])( *([-*_]))*$`)
+	markdownTableRule = regexp.MustCompile(`^\|?(\s*:?-+:?\s*\|)+\s*:?-*:?\s*$`)
+	// markdownUndecidable matches line starts that could still become a
+	// heading, list item, rule, quote or fence.
+	markdownUndecidable = regexp.MustCompile(`^(#{1,6}|[-*+_][-*_ ]*|\d+[.)]?|>|` + "`{1,2}|~{1,2}" + `)$`)
+)
+
+type markdownLineKind int
+
+const (
+	// markdownUndecided lines are buffered until their first characters
+	// tell what they are.
+	markdownUndecided markdownLineKind = iota
+	// markdownInline lines are written as they stream in.
+	markdownInline
+	// markdownBuffered lines, such as fences, code and table rows, are
+	// written when they are complete.
+	markdownBuffered
+)
+
+// MarkdownRenderer formats Markdown written to it in pieces, as answers
+// are streamed, for a terminal. Headings, lists, quotes, bold, italic and
+// inline code are styled as the text arrives. Fenced code blocks are
+// highlighted with chroma line by line, so constructs spanning lines such
+// as block comments may be highlighted as code of a single line. Tables
+// are aligned when their last row is complete. Call Flush when the answer
+// is complete.
+type MarkdownRenderer struct {
+	w         io.Writer
+	style     *chroma.Style
+	formatter chroma.Formatter
+
+	// partial holds the bytes of a rune split between writes.
+	partial []byte
+	line    strings.Builder
+	kind    markdownLineKind
+	// lineStyle is the style of the line being streamed, such as the
+	// heading style.
+	lineStyle string
+
+	fence string
+	lexer chroma.Lexer
+	table []string
+
+	bold       bool
+	italic     bool
+	inlineCode bool
+	// markers is a run of * waiting for the next character to tell
+	// whether it opens or closes emphasis.
+	markers string
+}
+
+// NewMarkdownRenderer returns a renderer writing to w that highlights code
+// with the chroma style theme, or DefaultTheme when it is unknown.
+func NewMarkdownRenderer(w io.Writer, theme string) *MarkdownRenderer {
+	style, ok := styles.Registry[strings.ToLower(theme)]
+	if !ok {
+		style = styles.Get(DefaultTheme)
+	}
+
+	formatter := formatters.TTY256
+	if colorTerm := os.Getenv("COLORTERM"); colorTerm == "truecolor" || colorTerm == "24bit" {
+		formatter = formatters.TTY16m
+	}
+
+	return &MarkdownRenderer{
+		w:         w,
+		style:     style,
+		formatter: formatter,
+	}
+}
+
+// IsTheme reports whether theme is the name of a chroma style.
+func IsTheme(theme string) bool {
+	_, ok := styles.Registry[strings.ToLower(theme)]
+
+	return ok
+}
+
+func (r *MarkdownRenderer) Write(p []byte) (int, error) {
+	data := append(r.partial, p...)
+	r.partial = nil
+
+	var out strings.Builder
+	for len(data) > 0 {
+		if !utf8.FullRune(data) {
+			r.partial = append([]byte(nil), data...)
+
+			break
+		}
+
+		c, size := utf8.DecodeRune(data)
+		data = data[size:]
+		r.writeRune(&out, c)
+	}
+
+	if _, err := io.WriteString(r.w, out.String()); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Flush writes the rest of the answer, such as an unterminated last line
+// or a table, and resets the terminal style.
+func (r *MarkdownRenderer) Flush() error {
+	var out strings.Builder
+
+	if len(r.partial) > 0 {
+		r.line.Write(r.partial)
+		r.partial = nil
+	}
+	if r.line.Len() > 0 || r.kind != markdownUndecided {
+		r.endLine(&out, false)
+	}
+	r.writeTable(&out)
+
+	r.fence = ""
+	r.lexer = nil
+
+	_, err := io.WriteString(r.w, out.String())
+
+	return err
+}
+
+func (r *MarkdownRenderer) writeRune(out *strings.Builder, c rune) {
+	if c == '\n' {
+		r.endLine(out, true)
+
+		return
+	}
+
+	switch r.kind {
+	case markdownInline:
+		r.writeInline(out, c)
+	case markdownBuffered:
+		r.line.WriteRune(c)
+	default:
+		r.line.WriteRune(c)
+		r.classify(out, false)
+	}
+}
+
+// classify decides how the buffered start of a line is written. With
+// complete, the whole line is buffered and must be decided.
+func (r *MarkdownRenderer) classify(out *strings.Builder, complete bool) {
+	text := r.line.String()
+
+	if r.fence != "" {
+		r.kind = markdownBuffered
+
+		return
+	}
+
+	trimmed := strings.TrimLeft(text, markdownIndent)
+	indent := text[:len(text)-len(trimmed)]
+	if trimmed == "" && !complete {
+		return
+	}
+
+	if strings.HasPrefix(trimmed, "|") {
+		r.kind = markdownBuffered
+
+		return
+	}
+	r.writeTable(out)
+
+	if fenceMarker(trimmed) != "" {
+		r.kind = markdownBuffered
+
+		return
+	}
+
+	if !complete && markdownUndecidable.MatchString(trimmed) {
+		return
+	}
+
+	r.line.Reset()
+	r.kind = markdownInline
+
+	switch {
+	case complete && markdownRule.MatchString(trimmed) && strings.Count(trimmed, trimmed[:1]) >= 3:
+		out.WriteString(ansiDim + strings.Repeat("─", markdownRuleWidth) + ansiReset)
+		// The rule is the whole line.
+		trimmed = ""
+	case markdownHeading.MatchString(trimmed):
+		marker := markdownHeading.FindString(trimmed)
+		r.lineStyle = ansiHeading
+		out.WriteString(indent + ansiHeading + marker)
+		trimmed = trimmed[len(marker):]
+	case markdownBullet.MatchString(trimmed):
+		out.WriteString(indent + ansiMarker + "•" + ansiReset + " ")
+		trimmed = trimmed[2:]
+	case markdownOrdered.MatchString(trimmed):
+		marker := markdownOrdered.FindString(trimmed)
+		out.WriteString(indent + ansiMarker + strings.TrimSpace(marker) + ansiReset + " ")
+		trimmed = trimmed[len(marker):]
+	case strings.HasPrefix(trimmed, ">"):
+		r.lineStyle = ansiDim
+		out.WriteString(indent + ansiDim + "│ ")
+		trimmed = strings.TrimPrefix(trimmed[1:], " ")
+	default:
+		out.WriteString(indent)
+	}
+
+	for _, c := range trimmed {
+		r.writeInline(out, c)
+	}
+}
+
+func (r *MarkdownRenderer) writeInline(out *strings.Builder, c rune) {
+	if r.inlineCode {
+		if c == '`' {
+			r.inlineCode = false
+			r.writeStyle(out)
+		} else {
+			out.WriteRune(c)
+		}
+
+		return
+	}
+
+	if c == '*' {
+		r.markers += "*"
+
+		return
+	}
+	r.writeMarkers(out, c)
+
+	if c == '`' {
+		r.inlineCode = true
+		r.writeStyle(out)
+
+		return
+	}
+
+	out.WriteRune(c)
+}
+
+// writeMarkers toggles emphasis for the pending run of *, given the
+// character after it. A run that would open emphasis before a space, as
+// in 2 * 3, is written as it is.
+func (r *MarkdownRenderer) writeMarkers(out *strings.Builder, next rune) {
+	markers := r.markers
+	if markers == "" {
+		return
+	}
+	r.markers = ""
+
+	opening := (len(markers) == 1 && !r.italic) || (len(markers) == 2 && !r.bold)
+	if len(markers) > 3 || (opening && (next == ' ' || next == 0)) {
+		out.WriteString(markers)
+
+		return
+	}
+
+	switch len(markers) {
+	case 1:
+		r.italic = !r.italic
+	case 2:
+		r.bold = !r.bold
+	default:
+		r.bold = !r.bold
+		r.italic = !r.italic
+	}
+	r.writeStyle(out)
+}
+
+// writeStyle switches to the style of the line with the emphasis in
+// effect.
+func (r *MarkdownRenderer) writeStyle(out *strings.Builder) {
+	out.WriteString(ansiReset + r.lineStyle)
+	if r.bold {
+		out.WriteString(ansiBold)
+	}
+	if r.italic {
+		out.WriteString(ansiItalic)
+	}
+	if r.inlineCode {
+		out.WriteString(ansiInlineCode)
+	}
+}
+
+func (r *MarkdownRenderer) endLine(out *strings.Builder, newline bool) {
+	if r.kind == markdownUndecided {
+		r.classify(out, true)
+	}
+
+	switch r.kind {
+	case markdownInline:
+		r.writeMarkers(out, 0)
+		if r.bold || r.italic || r.inlineCode || r.lineStyle != "" {
+			out.WriteString(ansiReset)
+		}
+		if newline {
+			out.WriteString("\n")
+		}
+	case markdownBuffered:
+		r.writeBufferedLine(out, r.line.String(), newline)
+	}
+
+	r.line.Reset()
+	r.kind = markdownUndecided
+	r.lineStyle = ""
+	r.bold = false
+	r.italic = false
+	r.inlineCode = false
+}
+
+// markdownIndent are the characters indenting a line, which classify and
+// writeBufferedLine must agree on.
+const markdownIndent = " \t"
+
+func (r *MarkdownRenderer) writeBufferedLine(out *strings.Builder, line string, newline bool) {
+	trimmed := strings.TrimLeft(line, markdownIndent)
+
+	switch {
+	case r.fence != "":
+		if strings.HasPrefix(trimmed, r.fence) && strings.TrimLeft(trimmed, r.fence[:1]) == "" {
+			r.fence = ""
+			r.lexer = nil
+			out.WriteString(ansiDim + line + ansiReset)
+			break
+		}
+
+		r.writeCode(out, line)
+	case strings.HasPrefix(trimmed, "|"):
+		r.table = append(r.table, line)
+
+		return
+	case fenceMarker(trimmed) == "":
+		// Not expected from classify, but written as it is rather than
+		// taken for a fence.
+		out.WriteString(line)
+	default:
+		r.fence = fenceMarker(trimmed)
+		language := strings.TrimSpace(strings.TrimLeft(trimmed, r.fence[:1]))
+		if i := strings.IndexAny(language, " {"); i >= 0 {
+			language = language[:i]
+		}
+		r.lexer = codeLexer(MarkdownBlock{Code: true, Language: language})
+		out.WriteString(ansiDim + line + ansiReset)
+	}
+
+	if newline {
+		out.WriteString("\n")
+	}
+}
+
+func (r *MarkdownRenderer) writeCode(out *strings.Builder, line string) {
+	iterator, err := r.lexer.Tokenise(nil, line+"\n")
+	if err != nil {
+		out.WriteString(line)
+
+		return
+	}
+
+	var code strings.Builder
+	if err := r.formatter.Format(&code, r.style, iterator); err != nil {
+		out.WriteString(line)
+
+		return
+	}
+	// The newline the lexers need is written by the caller.
+	out.WriteString(strings.ReplaceAll(code.String(), "\n", "") + ansiReset)
+}
+
+// writeTable writes the buffered table rows with aligned columns.
+func (r *MarkdownRenderer) writeTable(out *strings.Builder) {
+	if len(r.table) == 0 {
+		return
+	}
+	rows := r.table
+	r.table = nil
+
+	var cells [][]string
+	var widths []int
+	rule := -1
+	for i, row := range rows {
+		if markdownTableRule.MatchString(strings.TrimSpace(row)) {
+			if rule == -1 {
+				rule = i
+			}
+			cells = append(cells, nil)
+
+			continue
+		}
+
+		row = strings.TrimSpace(row)
+		row = strings.TrimSuffix(strings.TrimPrefix(row, "|"), "|")
+		var rowCells []string
+		for j, cell := range strings.Split(row, "|") {
+			cell = strings.TrimSpace(cell)
+			rowCells = append(rowCells, cell)
+			if j >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if width := runewidth.StringWidth(cell); width > widths[j] {
+				widths[j] = width
+			}
+		}
+		cells = append(cells, rowCells)
+	}
+
+	for i, rowCells := range cells {
+		if rowCells == nil {
+			var parts []string
+			for _, width := range widths {
+				parts = append(parts, strings.Repeat("─", width))
+			}
+			out.WriteString(ansiDim + strings.Join(parts, "─┼─") + ansiReset + "\n")
+
+			continue
+		}
+
+		for j, width := range widths {
+			if j > 0 {
+				out.WriteString(ansiDim + " │ " + ansiReset)
+			}
+			var cell string
+			if j < len(rowCells) {
+				cell = rowCells[j]
+			}
+			padding := strings.Repeat(" ", width-runewidth.StringWidth(cell))
+			if i < rule {
+				out.WriteString(ansiBold + cell + ansiReset + padding)
+			} else {
+				out.WriteString(cell + padding)
+			}
+		}
+		out.WriteString("\n")
+	}
+}
@@ -0,0 +1,113 @@
+package oax
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+func renderMarkdown(t *testing.T, chunks []string) string {
+	t.Helper()
+
+	buf := bytes.Buffer{}
+	renderer := NewMarkdownRenderer(&buf, DefaultTheme)
+	for _, chunk := range chunks {
+		if _, err := renderer.Write([]byte(chunk)); err != nil {
+			t.Fatalf("Error: Return err func: %v", err)
+		}
+	}
+	if err := renderer.Flush(); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+
+	return buf.String()
+}
+
+func TestMarkdownRenderer(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			"emphasis and inline code",
+			"Use **bold**, *it* and `code`, 2 * 3.",
+			"Use bold, it and code, 2 * 3.",
+		},
+		{
+			"lists and quote",
+			"- item\n1. one\n> quote",
+			"• item\n1. one\n│ quote",
+		},
+		{
+			"table",
+			"| a | bb |\n|---|---|\n| ccc | d |\nafter",
+			"a   │ bb\n────┼───\nccc │ d \nafter",
+		},
+		{
+			"code block",
+			"```go\nfunc **main**() {}\n```",
+			"```go\nfunc **main**() {}\n```",
+		},
+		{
+			"tab-indented code block",
+			"\t```go\n\tfunc main() {}\n\t```\nafter",
+			"\t```go\n\tfunc main() {}\n\t```\nafter",
+		},
+		{
+			"tab-indented table",
+			"\t| a | b |\nafter",
+			"a │ b\nafter",
+		},
+		{
+			"unterminated last line",
+			"# Title",
+			"# Title",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := ansiEscape.ReplaceAllString(renderMarkdown(t, []string{tc.input}), "")
+			if result != tc.expected {
+				t.Errorf("Expected %q but got %q", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestMarkdownRendererStreaming(t *testing.T) {
+	input := "# Title\n\nSome **bold** and `code`.\n\n| a | b |\n|---|---|\n| 1 | 2 |\n\n```go\nfunc main() {}\n```\n\nJapanese: 日本語"
+
+	expected := renderMarkdown(t, []string{input})
+
+	var chunks []string
+	data := []byte(input)
+	for i := 0; i < len(data); i += 3 {
+		end := i + 3
+		if end > len(data) {
+			end = len(data)
+		}
+		chunks = append(chunks, string(data[i:end]))
+	}
+
+	result := renderMarkdown(t, chunks)
+	if result != expected {
+		t.Errorf("Expected %q but got %q", expected, result)
+	}
+	if !strings.Contains(result, "日本語") {
+		t.Errorf("Expected %q to contain %q", result, "日本語")
+	}
+}
+
+func TestIsTheme(t *testing.T) {
+	if !IsTheme(DefaultTheme) {
+		t.Errorf("Expected %q to be a theme", DefaultTheme)
+	}
+	if IsTheme("oax-missing-theme") {
+		t.Errorf("Expected %q not to be a theme", "oax-missing-theme")
+	}
+}
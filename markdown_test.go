@@ -0,0 +1,53 @@
+package oax
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitFencedCodeBlocks(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected []MarkdownBlock
+	}{
+		{
+			"text only",
+			"hello\nworld",
+			[]MarkdownBlock{{Text: "hello\nworld"}},
+		},
+		{
+			"code between text",
+			"before\n```go\nfunc main() {}\n```\nafter",
+			[]MarkdownBlock{
+				{Text: "before"},
+				{Code: true, Language: "go", Text: "func main() {}"},
+				{Text: "after"},
+			},
+		},
+		{
+			"nested shorter fence",
+			"````md\n```go\nx\n```\n````",
+			[]MarkdownBlock{{Code: true, Language: "md", Text: "```go\nx\n```"}},
+		},
+		{
+			"unterminated fence",
+			"~~~python\nprint(1)",
+			[]MarkdownBlock{{Code: true, Language: "python", Text: "print(1)"}},
+		},
+		{
+			"inline code is not a fence",
+			"```a``` b",
+			[]MarkdownBlock{{Text: "```a``` b"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := SplitFencedCodeBlocks(tc.input)
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("Expected %+v but got %+v", tc.expected, result)
+			}
+		})
+	}
+}
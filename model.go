@@ -0,0 +1,110 @@
+package oax
+
+import (
+	"fmt"
+	"sort"
+)
+
+const ProviderOpenAI = "openai"
+
+// ModelAlias is a [models.<alias>] table of the settings, which names a
+// model with its profile and default parameters so that the alias can be
+// used wherever a model is accepted.
+type ModelAlias struct {
+	Provider string `toml:"provider"`
+	// Profile is used instead of the default profile unless --profile or
+	// OAX_PROFILE is given.
+	Profile string `toml:"profile"`
+	Model   string `toml:"model"`
+	// ContextSize is the number of tokens the model accepts. oax warns when
+	// a conversation is larger.
+	ContextSize int         `toml:"contextSize"`
+	Params      ModelParams `toml:"params"`
+}
+
+// ModelParams are request parameters of the chat completions endpoint.
+// Unset parameters use the defaults of the API.
+type ModelParams struct {
+	Temperature      *float64 `toml:"temperature"`
+	TopP             *float64 `toml:"topP"`
+	MaxTokens        *int     `toml:"maxTokens"`
+	PresencePenalty  *float64 `toml:"presencePenalty"`
+	FrequencyPenalty *float64 `toml:"frequencyPenalty"`
+}
+
+// ResolvedModel is the model used for requests. Alias is empty when the
+// model was given by its ID.
+type ResolvedModel struct {
+	Alias string
+	ModelAlias
+}
+
+// ResolveModel expands chat.model, which may have been set with --model or
+// OAX_MODEL, when it is an alias. The profile of the alias is selected
+// unless a profile was given explicitly.
+func (c *Config) ResolveModel() (ResolvedModel, error) {
+	name := c.Settings.Chat.Model
+
+	alias, ok := c.Settings.Models[name]
+	if !ok {
+		return ResolvedModel{ModelAlias: ModelAlias{Provider: ProviderOpenAI, Model: name}}, nil
+	}
+
+	if alias.Provider == "" {
+		alias.Provider = ProviderOpenAI
+	}
+	if alias.Provider != ProviderOpenAI {
+		return ResolvedModel{}, fmt.Errorf("model %s: unsupported provider %s (supported: %s)", name, alias.Provider, ProviderOpenAI)
+	}
+	if alias.Model == "" {
+		return ResolvedModel{}, fmt.Errorf("model %s: model is not set in [models.%s]", name, name)
+	}
+
+	if alias.Profile != "" && c.ProfileName == "" {
+		c.ProfileName = alias.Profile
+		if c.Sources == nil {
+			c.Sources = map[string]string{}
+		}
+		c.Sources["profile"] = fmt.Sprintf("model alias %s", name)
+	}
+
+	return ResolvedModel{Alias: name, ModelAlias: alias}, nil
+}
+
+// ModelAliasNames returns the names of the model aliases in order.
+func (s Settings) ModelAliasNames() []string {
+	var names []string
+	for name := range s.Models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// ModelChoices returns the models a chat with the profile can switch to:
+// the model aliases that use the profile or no profile, then the chat
+// models cached for the profile by oax profile test and oax models.
+func (c *Config) ModelChoices(profileName string) []ResolvedModel {
+	var choices []ResolvedModel
+	for _, name := range c.Settings.ModelAliasNames() {
+		alias := c.Settings.Models[name]
+		if alias.Provider == "" {
+			alias.Provider = ProviderOpenAI
+		}
+		if alias.Provider != ProviderOpenAI || alias.Model == "" || (alias.Profile != "" && alias.Profile != profileName) {
+			continue
+		}
+		choices = append(choices, ResolvedModel{Alias: name, ModelAlias: alias})
+	}
+
+	cache, err := OpenModelCache(c.ConfigDir)
+	if err != nil {
+		return choices
+	}
+	for _, model := range cache.ChatModels(profileName) {
+		choices = append(choices, ResolvedModel{ModelAlias: ModelAlias{Provider: ProviderOpenAI, Model: model}})
+	}
+
+	return choices
+}
@@ -0,0 +1,128 @@
+package oax
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const ModelCacheFileName = "models.json"
+
+// ModelCache holds the IDs of every model each profile can access, as
+// listed by oax profile test and oax models, to validate and complete
+// --model.
+type ModelCache struct {
+	FilePath string                       `json:"-"`
+	Profiles map[string]ModelCacheProfile `json:"profiles"`
+}
+
+type ModelCacheProfile struct {
+	UpdatedAt time.Time `json:"updatedAt"`
+	Models    []string  `json:"models"`
+}
+
+// OpenModelCache reads the cache in configDir. A missing cache is empty.
+func OpenModelCache(configDir string) (*ModelCache, error) {
+	cache := &ModelCache{
+		FilePath: filepath.Join(configDir, ModelCacheFileName),
+		Profiles: map[string]ModelCacheProfile{},
+	}
+
+	data, err := os.ReadFile(cache.FilePath)
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, cache)
+	if err != nil {
+		return nil, fmt.Errorf("invalid model cache %s: %w", cache.FilePath, err)
+	}
+	if cache.Profiles == nil {
+		cache.Profiles = map[string]ModelCacheProfile{}
+	}
+
+	return cache, nil
+}
+
+func (m *ModelCache) Set(profile string, models []string) {
+	models = append([]string(nil), models...)
+	sort.Strings(models)
+
+	m.Profiles[profile] = ModelCacheProfile{
+		UpdatedAt: time.Now(),
+		Models:    models,
+	}
+}
+
+// Models returns the cached models of profile, or nil when the profile has
+// not been tested.
+func (m *ModelCache) Models(profile string) []string {
+	return m.Profiles[profile].Models
+}
+
+// ChatModels returns the cached models of profile that IsChatModel guesses
+// to be chat models, for listing them.
+func (m *ModelCache) ChatModels(profile string) []string {
+	var models []string
+	for _, model := range m.Models(profile) {
+		if IsChatModel(model) {
+			models = append(models, model)
+		}
+	}
+
+	return models
+}
+
+func (m *ModelCache) Save() error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(m.FilePath, data, 0644, false)
+}
+
+// ValidateModel returns an error when the models of profile are cached and
+// model is not one of them. The cache may be out of date, so the error is
+// meant as a warning.
+func (m *ModelCache) ValidateModel(profile string, model string) error {
+	models := m.Models(profile)
+	if models == nil || containsString(models, model) {
+		return nil
+	}
+
+	message := fmt.Sprintf("model %s is not in the cached models of profile %s", model, profile)
+	if suggestion := suggest(model, m.ChatModels(profile)); suggestion != "" {
+		message += fmt.Sprintf(" (did you mean %s?)", suggestion)
+	}
+
+	return fmt.Errorf("%s. Run `oax profile test %s` to refresh the model list", message, profile)
+}
+
+// IsChatModel guesses from its name whether a model ID from the models
+// endpoint can be used with the chat completions endpoint. It is only used
+// to decide which models to list; fine-tuned models start with ft:.
+func IsChatModel(id string) bool {
+	id = strings.TrimPrefix(id, "ft:")
+
+	for _, exclude := range []string{"instruct", "realtime", "transcribe", "tts", "image"} {
+		if strings.Contains(id, exclude) {
+			return false
+		}
+	}
+
+	for _, prefix := range []string{"gpt-", "chatgpt-", "o1", "o3", "o4"} {
+		if strings.HasPrefix(id, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,69 @@
+package oax
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestModelCache(t *testing.T) {
+	configDir := t.TempDir()
+
+	cache, err := OpenModelCache(configDir)
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if err := cache.ValidateModel("me", "gpt-4"); err != nil {
+		t.Errorf("Expected no error for an untested profile but got %v", err)
+	}
+
+	cache.Set("me", []string{"gpt-4", "gpt-3.5-turbo", "text-embedding-ada-002"})
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+
+	reopened, err := OpenModelCache(configDir)
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+
+	expected := []string{"gpt-3.5-turbo", "gpt-4"}
+	if models := reopened.ChatModels("me"); !reflect.DeepEqual(models, expected) {
+		t.Errorf("Expected %q but got %q", expected, models)
+	}
+	if err := reopened.ValidateModel("me", "text-embedding-ada-002"); err != nil {
+		t.Errorf("Expected a cached model that is not a chat model to be valid but got %v", err)
+	}
+	if err := reopened.ValidateModel("me", "gpt-4"); err != nil {
+		t.Errorf("Expected no error but got %v", err)
+	}
+
+	err = reopened.ValidateModel("me", "gpt-5")
+	if err == nil || !strings.Contains(err.Error(), "did you mean gpt-4?") {
+		t.Errorf("Expected a suggestion but got %v", err)
+	}
+}
+
+func TestIsChatModel(t *testing.T) {
+	testCases := []struct {
+		id       string
+		expected bool
+	}{
+		{"gpt-3.5-turbo", true},
+		{"gpt-4-32k-0314", true},
+		{"gpt-3.5-turbo-instruct", false},
+		{"text-embedding-ada-002", false},
+		{"whisper-1", false},
+		{"ft:gpt-3.5-turbo-0613:acme::7qTVM5AR", true},
+		{"gpt-4o-search-preview", true},
+		{"gpt-4o-audio-preview", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			if result := IsChatModel(tc.id); result != tc.expected {
+				t.Errorf("Expected %v but got %v", tc.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package oax
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pelletier/go-toml"
+)
+
+func TestResolveModel(t *testing.T) {
+	settings := &Settings{}
+	if err := toml.Unmarshal([]byte(`[models.fast]
+  profile = "work"
+  model = "gpt-3.5-turbo"
+  contextSize = 4096
+
+  [models.fast.params]
+    temperature = 0.2
+    maxTokens = 512
+
+[models.other]
+  provider = "other"
+  model = "other-model"
+`), settings); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+
+	testCases := []struct {
+		name            string
+		model           string
+		profileName     string
+		expectedModel   string
+		expectedProfile string
+		expectedErr     bool
+	}{
+		{"model id", "gpt-4", "", "gpt-4", "", false},
+		{"alias", "fast", "", "gpt-3.5-turbo", "work", false},
+		{"alias with profile flag", "fast", "me", "gpt-3.5-turbo", "me", false},
+		{"unsupported provider", "other", "", "", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &Config{Settings: *settings, ProfileName: tc.profileName}
+			config.Settings.Chat.Model = tc.model
+
+			model, err := config.ResolveModel()
+			if tc.expectedErr {
+				if err == nil {
+					t.Fatalf("Expected error but got %+v", model)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("Error: Return err func: %v", err)
+			}
+			if model.Model != tc.expectedModel {
+				t.Errorf("Expected %q but got %q", tc.expectedModel, model.Model)
+			}
+			if config.ProfileName != tc.expectedProfile {
+				t.Errorf("Expected %q but got %q", tc.expectedProfile, config.ProfileName)
+			}
+		})
+	}
+
+	config := &Config{Settings: *settings}
+	config.Settings.Chat.Model = "fast"
+	model, err := config.ResolveModel()
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if model.ContextSize != 4096 || model.Params.Temperature == nil || *model.Params.Temperature != 0.2 || model.Params.MaxTokens == nil || *model.Params.MaxTokens != 512 {
+		t.Errorf("Expected the context size and params of the alias but got %+v", model)
+	}
+}
+
+func TestModelChoices(t *testing.T) {
+	config := &Config{ConfigDir: t.TempDir()}
+	if err := toml.Unmarshal([]byte(`[models.fast]
+  model = "gpt-3.5-turbo"
+
+[models.work]
+  profile = "work"
+  model = "gpt-4"
+
+[models.other]
+  provider = "other"
+  model = "other-model"
+`), &config.Settings); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+
+	cache, err := OpenModelCache(config.ConfigDir)
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	cache.Set("me", []string{"gpt-4", "gpt-3.5-turbo"})
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+
+	var names []string
+	for _, choice := range config.ModelChoices("me") {
+		names = append(names, choice.Alias+"="+choice.Model)
+	}
+	expected := "fast=gpt-3.5-turbo,=gpt-3.5-turbo,=gpt-4"
+	if strings.Join(names, ",") != expected {
+		t.Errorf("Expected %q but got %q", expected, strings.Join(names, ","))
+	}
+}
@@ -32,18 +32,31 @@ type requestBody struct {
 	Model    string    `json:"model"`
 	Messages []Message `json:"messages"`
 	Stream   bool      `json:"stream"`
+	ChatParams
+}
+
+// ChatParams are optional request parameters. Nil parameters are omitted
+// so that the API defaults apply.
+type ChatParams struct {
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"top_p,omitempty"`
+	MaxTokens        *int     `json:"max_tokens,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
 }
 
 type ChatCreateCompletionOption struct {
 	Model    string
 	Messages []Message
+	Params   ChatParams
 }
 
 func (c *Client) ChatCreateCompletionSubscribeWithContext(ctx context.Context, opt *ChatCreateCompletionOption, handler func(msg *ChatCompletionResponse, err error) error) error {
 	body := requestBody{
-		Messages: opt.Messages,
-		Model:    opt.Model,
-		Stream:   true,
+		Messages:   opt.Messages,
+		Model:      opt.Model,
+		Stream:     true,
+		ChatParams: opt.Params,
 	}
 
 	reqBytes, err := json.Marshal(body)
@@ -57,7 +57,7 @@ func (t *customTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	req.Header.Set("Content-Type", "application/json")
 
 	if t.openAISettings.OrganizationID != "" {
-		req.Header.Set("OpenAI-Organization:", t.openAISettings.OrganizationID)
+		req.Header.Set("OpenAI-Organization", t.openAISettings.OrganizationID)
 	}
 
 	resp, err := t.RoundTripper.RoundTrip(req)
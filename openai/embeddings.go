@@ -0,0 +1,71 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+)
+
+const DefaultEmbeddingModel = "text-embedding-ada-002"
+
+type EmbeddingsResponse struct {
+	Object string      `json:"object"`
+	Model  string      `json:"model"`
+	Data   []Embedding `json:"data"`
+}
+
+type Embedding struct {
+	Object    string    `json:"object"`
+	Index     int       `json:"index"`
+	Embedding []float32 `json:"embedding"`
+}
+
+type embeddingsRequestBody struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type CreateEmbeddingsOption struct {
+	Model string
+	Input []string
+}
+
+func (c *Client) CreateEmbeddingsWithContext(ctx context.Context, opt *CreateEmbeddingsOption) (*EmbeddingsResponse, error) {
+	model := opt.Model
+	if model == "" {
+		model = DefaultEmbeddingModel
+	}
+
+	reqBytes, err := json.Marshal(embeddingsRequestBody{
+		Model: model,
+		Input: opt.Input,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", APIBaseEndpoint+"/v1/embeddings", bytes.NewBuffer(reqBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.client.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var embeddings EmbeddingsResponse
+	err = json.NewDecoder(res.Body).Decode(&embeddings)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(embeddings.Data, func(i, j int) bool {
+		return embeddings.Data[i].Index < embeddings.Data[j].Index
+	})
+
+	return &embeddings, nil
+}
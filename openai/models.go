@@ -0,0 +1,49 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+)
+
+type Model struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+type ModelsResponse struct {
+	Object string  `json:"object"`
+	Data   []Model `json:"data"`
+	// Organization is the organization the request was billed to, from the
+	// OpenAI-Organization response header.
+	Organization string `json:"-"`
+}
+
+func (c *Client) ListModelsWithContext(ctx context.Context) (*ModelsResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", APIBaseEndpoint+"/v1/models", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.client.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var models ModelsResponse
+	err = json.NewDecoder(res.Body).Decode(&models)
+	if err != nil {
+		return nil, err
+	}
+	models.Organization = res.Header.Get("OpenAI-Organization")
+
+	sort.Slice(models.Data, func(i, j int) bool {
+		return models.Data[i].ID < models.Data[j].ID
+	})
+
+	return &models, nil
+}
@@ -0,0 +1,309 @@
+package oax
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+)
+
+// ErrorMultipleDefaultProfiles is returned when no profile is given and
+// more than one profile is marked default.
+type ErrorMultipleDefaultProfiles struct {
+	Names []string
+}
+
+func (e *ErrorMultipleDefaultProfiles) Error() string {
+	return fmt.Sprintf("multiple default profiles: %s. Choose one with `oax profile set-default <name>`", strings.Join(e.Names, ", "))
+}
+
+// DefaultProfile returns the profile marked default, and an error when
+// there is none or more than one.
+func DefaultProfile(profiles []Profile) (Profile, error) {
+	var defaults []Profile
+	for _, profile := range profiles {
+		if profile.Default {
+			defaults = append(defaults, profile)
+		}
+	}
+
+	switch len(defaults) {
+	case 0:
+		return Profile{}, fmt.Errorf("no default profile")
+	case 1:
+		return defaults[0], nil
+	default:
+		var names []string
+		for _, profile := range defaults {
+			names = append(names, profile.Name)
+		}
+
+		return Profile{}, &ErrorMultipleDefaultProfiles{Names: names}
+	}
+}
+
+// AddProfile appends profile to profiles.toml. When it is the default
+// profile, or the first one, the other profiles stop being default.
+func AddProfile(profile Profile) error {
+	return editProfiles(func(file *profilesFile) error {
+		for _, block := range file.blocks {
+			if block.profile.Name == profile.Name {
+				return fmt.Errorf("profile %s already exists", profile.Name)
+			}
+		}
+
+		if len(file.blocks) == 0 {
+			profile.Default = true
+		}
+		if profile.Default {
+			if err := file.clearDefault(); err != nil {
+				return err
+			}
+		}
+
+		file.append(encodeProfiles([]Profile{profile}))
+
+		return nil
+	})
+}
+
+// RemoveProfile removes the profile from profiles.toml and returns it.
+func RemoveProfile(name string) (Profile, error) {
+	var removed Profile
+
+	err := editProfiles(func(file *profilesFile) error {
+		for _, block := range file.blocks {
+			if block.profile.Name == name {
+				removed = block.profile
+				file.remove(block)
+
+				return nil
+			}
+		}
+
+		return fmt.Errorf("invalid profile %s", name)
+	})
+
+	return removed, err
+}
+
+// SetDefaultProfile marks only the named profile as default.
+func SetDefaultProfile(name string) error {
+	return editProfiles(func(file *profilesFile) error {
+		for i, block := range file.blocks {
+			if block.profile.Name == name {
+				return file.setDefault(i)
+			}
+		}
+
+		return fmt.Errorf("invalid profile %s", name)
+	})
+}
+
+// profilesFile is profiles.toml as lines, so that it can be changed
+// without losing the comments and unknown keys that go-toml drops.
+type profilesFile struct {
+	lines  []string
+	blocks []profileBlock
+}
+
+// profileBlock is a [[profiles]] table. start is the index of its header
+// line and end that of the next header, or the number of lines.
+type profileBlock struct {
+	profile Profile
+	tree    *toml.Tree
+	start   int
+	end     int
+}
+
+var profileDefaultLine = regexp.MustCompile(`^(\s*default\s*=\s*)(true|false)(.*)$`)
+
+// editProfiles calls edit with profiles.toml and writes the result. The
+// file is locked from reading to writing so that concurrent oax processes
+// do not lose each other's changes.
+func editProfiles(edit func(file *profilesFile) error) error {
+	lock, err := lockFile(profileFilePath)
+	if err != nil {
+		return err
+	}
+	defer lock.unlock()
+
+	// loadProfiles creates profiles.toml when it does not exist.
+	if _, err := loadProfiles(); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(profileFilePath)
+	if err != nil {
+		return err
+	}
+
+	file, err := parseProfilesFile(string(data))
+	if err != nil {
+		return err
+	}
+
+	if err := edit(file); err != nil {
+		return err
+	}
+
+	return saveProfiles([]byte(strings.Join(file.lines, "\n")))
+}
+
+func parseProfilesFile(data string) (*profilesFile, error) {
+	tree, err := toml.Load(data)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &profilesFile{lines: strings.Split(data, "\n")}
+
+	trees, _ := tree.Get("profiles").([]*toml.Tree)
+	for i, profileTree := range trees {
+		var profile Profile
+		if err := profileTree.Unmarshal(&profile); err != nil {
+			return nil, err
+		}
+
+		end := len(file.lines)
+		if i+1 < len(trees) {
+			end = trees[i+1].Position().Line - 1
+		}
+
+		file.blocks = append(file.blocks, profileBlock{
+			profile: profile,
+			tree:    profileTree,
+			start:   profileTree.Position().Line - 1,
+			end:     end,
+		})
+	}
+
+	return file, nil
+}
+
+// setDefault sets default = true on the block at index and removes it from
+// the others.
+func (f *profilesFile) setDefault(index int) error {
+	return f.markDefault(func(i int) bool { return i == index })
+}
+
+// clearDefault removes default = true from all profiles.
+func (f *profilesFile) clearDefault() error {
+	return f.markDefault(func(int) bool { return false })
+}
+
+// markDefault sets default of each block to isDefault of its index. Blocks
+// are changed from the last one so that the line indexes of the earlier
+// ones stay valid.
+func (f *profilesFile) markDefault(isDefaultAt func(i int) bool) error {
+	for i := len(f.blocks) - 1; i >= 0; i-- {
+		block := f.blocks[i]
+		isDefault := isDefaultAt(i)
+
+		if !block.tree.Has("default") {
+			if isDefault {
+				// default goes after the name, or after the header of a
+				// profile without a name.
+				line := block.start
+				if block.tree.Has("name") {
+					line = block.tree.GetPosition("name").Line - 1
+				}
+				indent := f.lines[line][:len(f.lines[line])-len(strings.TrimLeft(f.lines[line], " \t"))]
+				f.insert(line+1, indent+"default = true")
+			}
+
+			continue
+		}
+
+		line := block.tree.GetPosition("default").Line - 1
+		match := profileDefaultLine.FindStringSubmatch(f.lines[line])
+		if match == nil {
+			return fmt.Errorf("%s:%d: cannot change default of profile %s", profileFilePath, line+1, block.profile.Name)
+		}
+		f.lines[line] = match[1] + strconv.FormatBool(isDefault) + match[3]
+	}
+
+	return nil
+}
+
+// remove deletes the lines of block, together with the comments directly
+// above its header. Comments at its end describe the next profile and are
+// kept.
+func (f *profilesFile) remove(block profileBlock) {
+	start := block.start
+	for start > 0 && isTomlComment(f.lines[start-1]) {
+		start--
+	}
+
+	end := block.end
+	for end > block.start+1 && (isTomlComment(f.lines[end-1]) || strings.TrimSpace(f.lines[end-1]) == "") {
+		end--
+	}
+	for end < block.end && strings.TrimSpace(f.lines[end]) == "" {
+		end++
+	}
+
+	f.lines = append(f.lines[:start], f.lines[end:]...)
+}
+
+func (f *profilesFile) insert(index int, line string) {
+	f.lines = append(f.lines[:index], append([]string{line}, f.lines[index:]...)...)
+}
+
+// append adds data after the last line, separated by an empty line.
+func (f *profilesFile) append(data []byte) {
+	for len(f.lines) > 0 && strings.TrimSpace(f.lines[len(f.lines)-1]) == "" {
+		f.lines = f.lines[:len(f.lines)-1]
+	}
+	if len(f.lines) > 0 {
+		f.lines = append(f.lines, "")
+	}
+
+	f.lines = append(f.lines, strings.Split(string(data), "\n")...)
+}
+
+func isTomlComment(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "#")
+}
+
+// saveProfiles rewrites profiles.toml, readable only by the user.
+func saveProfiles(data []byte) error {
+	return writeFileAtomic(profileFilePath, data, 0600, true)
+}
+
+func encodeProfiles(profiles []Profile) []byte {
+	var builder strings.Builder
+
+	for i, profile := range profiles {
+		if i > 0 {
+			builder.WriteString("\n")
+		}
+		builder.WriteString("[[profiles]]\n")
+
+		fields := []struct {
+			key   string
+			value interface{}
+			set   bool
+		}{
+			{"name", profile.Name, true},
+			{"description", profile.Description, profile.Description != ""},
+			{"apiKey", profile.ApiKey, profile.ApiKey != ""},
+			{"apiKeyEnv", profile.ApiKeyEnv, profile.ApiKeyEnv != ""},
+			{"apiKeyCommand", profile.ApiKeyCommand, profile.ApiKeyCommand != ""},
+			{"apiKeyStore", profile.ApiKeyStore, profile.ApiKeyStore},
+			{"organizationId", profile.OrganizationID, profile.OrganizationID != ""},
+			{"default", profile.Default, profile.Default},
+		}
+
+		for _, field := range fields {
+			if field.set {
+				fmt.Fprintf(&builder, "  %s = %s\n", field.key, tomlValue(field.value))
+			}
+		}
+	}
+
+	return []byte(builder.String())
+}
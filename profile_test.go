@@ -0,0 +1,197 @@
+package oax
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestProfileCommands(t *testing.T) {
+	setConfigDir(t.TempDir())
+	if err := os.WriteFile(profileFilePath, nil, 0600); err != nil {
+		t.Fatalf("Error: Cannot write file: %v", err)
+	}
+
+	if err := AddProfile(Profile{Name: "me", ApiKey: "sk-me"}); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if err := AddProfile(Profile{Name: "work", ApiKeyEnv: "WORK_API_KEY", OrganizationID: "org-1"}); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if err := AddProfile(Profile{Name: "me"}); err == nil {
+		t.Errorf("Expected error for a duplicate profile")
+	}
+	if err := SetDefaultProfile("work"); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if err := SetDefaultProfile("none"); err == nil {
+		t.Errorf("Expected error for an unknown profile")
+	}
+
+	profiles, err := loadProfiles()
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+
+	expected := []Profile{
+		{Name: "me", ApiKey: "sk-me"},
+		{Name: "work", ApiKeyEnv: "WORK_API_KEY", OrganizationID: "org-1", Default: true},
+	}
+	if !reflect.DeepEqual(profiles.Profiles, expected) {
+		t.Errorf("Expected %+v but got %+v", expected, profiles.Profiles)
+	}
+
+	removed, err := RemoveProfile("me")
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if removed.Name != "me" {
+		t.Errorf("Expected %q but got %q", "me", removed.Name)
+	}
+
+	profiles, err = loadProfiles()
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if len(profiles.Profiles) != 1 || profiles.Profiles[0].Name != "work" {
+		t.Errorf("Expected only profile work but got %+v", profiles.Profiles)
+	}
+}
+
+func TestProfileCommandsKeepComments(t *testing.T) {
+	setConfigDir(t.TempDir())
+	if err := os.WriteFile(profileFilePath, []byte(`# My profiles
+[[profiles]]
+name = "personal"
+apiKey = "sk-xxxx"
+# apiKeyEnv = "OPENAI_API_KEY"
+default = true # the usual one
+proxy = "http://localhost:8080"
+
+# Work account
+[[profiles]]
+  name = "work"
+  apiKeyCommand = "pass show work"
+`), 0600); err != nil {
+		t.Fatalf("Error: Cannot write file: %v", err)
+	}
+
+	if err := SetDefaultProfile("work"); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if err := AddProfile(Profile{Name: "ci", ApiKeyEnv: "CI_API_KEY"}); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+
+	data, err := os.ReadFile(profileFilePath)
+	if err != nil {
+		t.Fatalf("Error: Cannot read file: %v", err)
+	}
+
+	expected := `# My profiles
+[[profiles]]
+name = "personal"
+apiKey = "sk-xxxx"
+# apiKeyEnv = "OPENAI_API_KEY"
+default = false # the usual one
+proxy = "http://localhost:8080"
+
+# Work account
+[[profiles]]
+  name = "work"
+  default = true
+  apiKeyCommand = "pass show work"
+
+[[profiles]]
+  name = "ci"
+  apiKeyEnv = "CI_API_KEY"
+`
+	if string(data) != expected {
+		t.Errorf("Expected %q but got %q", expected, string(data))
+	}
+
+	if _, err := RemoveProfile("personal"); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+
+	data, err = os.ReadFile(profileFilePath)
+	if err != nil {
+		t.Fatalf("Error: Cannot read file: %v", err)
+	}
+
+	expected = `# Work account
+[[profiles]]
+  name = "work"
+  default = true
+  apiKeyCommand = "pass show work"
+
+[[profiles]]
+  name = "ci"
+  apiKeyEnv = "CI_API_KEY"
+`
+	if string(data) != expected {
+		t.Errorf("Expected %q but got %q", expected, string(data))
+	}
+}
+
+func TestProfileCommandsUnnamedProfile(t *testing.T) {
+	setConfigDir(t.TempDir())
+	if err := os.WriteFile(profileFilePath, []byte(`[[profiles]]
+  apiKey = "sk-xxxx"
+
+[[profiles]]
+  apiKey = "sk-yyyy"
+  default = false
+
+[[profiles]]
+  name = "work"
+  default = true
+`), 0600); err != nil {
+		t.Fatalf("Error: Cannot write file: %v", err)
+	}
+
+	if err := AddProfile(Profile{Name: "ci", ApiKeyEnv: "CI_API_KEY", Default: true}); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+
+	data, err := os.ReadFile(profileFilePath)
+	if err != nil {
+		t.Fatalf("Error: Cannot read file: %v", err)
+	}
+
+	expected := `[[profiles]]
+  apiKey = "sk-xxxx"
+
+[[profiles]]
+  apiKey = "sk-yyyy"
+  default = false
+
+[[profiles]]
+  name = "work"
+  default = false
+
+[[profiles]]
+  name = "ci"
+  apiKeyEnv = "CI_API_KEY"
+  default = true
+`
+	if string(data) != expected {
+		t.Errorf("Expected %q but got %q", expected, string(data))
+	}
+}
+
+func TestDefaultProfile(t *testing.T) {
+	_, err := DefaultProfile([]Profile{
+		{Name: "me", Default: true},
+		{Name: "work", Default: true},
+	})
+
+	var multipleDefaults *ErrorMultipleDefaultProfiles
+	if !errors.As(err, &multipleDefaults) {
+		t.Fatalf("Expected ErrorMultipleDefaultProfiles but got %v", err)
+	}
+	if !reflect.DeepEqual(multipleDefaults.Names, []string{"me", "work"}) {
+		t.Errorf("Expected %q but got %q", []string{"me", "work"}, multipleDefaults.Names)
+	}
+}
@@ -0,0 +1,176 @@
+package oax
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pelletier/go-toml"
+)
+
+const (
+	// ProjectDirName is the per-project directory holding settings.toml
+	// and, by default, the chat logs of the project.
+	ProjectDirName = ".oax"
+	// ProjectFileName is an alternative to ProjectDirName for projects that
+	// only need settings.
+	ProjectFileName = ".oax.toml"
+)
+
+// Project is the nearest directory, walking up from the working directory,
+// that contains a .oax directory or a .oax.toml file.
+type Project struct {
+	Root            string
+	SettingFilePath string
+	// ChatLogDir is the default chat log directory of the project, or
+	// empty when the global one is used.
+	ChatLogDir string
+}
+
+// FindProject walks up from dir like git does and returns nil when no
+// project is found.
+func FindProject(dir string) (*Project, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		projectDir := filepath.Join(dir, ProjectDirName)
+		if info, err := os.Stat(projectDir); err == nil && info.IsDir() {
+			return &Project{
+				Root:            dir,
+				SettingFilePath: filepath.Join(projectDir, "settings.toml"),
+				ChatLogDir:      filepath.Join(projectDir, "chat-log"),
+			}, nil
+		}
+
+		projectFile := filepath.Join(dir, ProjectFileName)
+		if info, err := os.Stat(projectFile); err == nil && !info.IsDir() {
+			return &Project{
+				Root:            dir,
+				SettingFilePath: projectFile,
+			}, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// loadSetting reads the project settings and returns the parsed tree, which
+// is nil when a .oax directory has no settings file.
+func (p *Project) loadSetting() (*Settings, *toml.Tree, error) {
+	setting := &Settings{}
+
+	if _, err := os.Stat(p.SettingFilePath); os.IsNotExist(err) {
+		setting.Setting.ChatLogDir = p.ChatLogDir
+
+		return setting, nil, nil
+	}
+
+	configTree, err := toml.LoadFile(p.SettingFilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = configTree.Unmarshal(setting)
+	if err != nil {
+		return nil, nil, err
+	}
+	setTemplateFilePath(setting.Chat.Templates, p.SettingFilePath)
+	setTemplateProjectRoot(setting.Chat.Templates, p.Root)
+
+	// Paths in project settings are relative to the project root.
+	if chatLogDir := setting.Setting.ChatLogDir; chatLogDir != "" {
+		chatLogDir, err = replaceTildeWithHomedir(chatLogDir)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !filepath.IsAbs(chatLogDir) {
+			chatLogDir = filepath.Join(p.Root, chatLogDir)
+		}
+		setting.Setting.ChatLogDir = chatLogDir
+	} else {
+		setting.Setting.ChatLogDir = p.ChatLogDir
+	}
+
+	return setting, configTree, nil
+}
+
+// projectSettingKeys are the settings a project can set. Other settings,
+// such as the editor that oax runs or semantic search that uploads chat
+// logs, are not taken from a repository that may not be trusted.
+var projectSettingKeys = []string{
+	"setting.chatLogDir",
+	"chat.model",
+	"chat.defaultTemplate",
+	"chat.templates",
+}
+
+// mergeProjectSettings overrides the values of base with the project
+// settings in override.
+func mergeProjectSettings(base *Settings, override *Settings) {
+	mergeString(&base.Setting.ChatLogDir, override.Setting.ChatLogDir)
+	mergeString(&base.Chat.Model, override.Chat.Model)
+	mergeString(&base.Chat.DefaultTemplate, override.Chat.DefaultTemplate)
+	mergeTemplates(&base.Chat.Templates, override.Chat.Templates)
+}
+
+// ignoredProjectSettings lists the settings set in a project settings file
+// that are not in projectSettingKeys.
+func ignoredProjectSettings(tree *toml.Tree) []string {
+	if tree == nil {
+		return nil
+	}
+
+	var ignored []string
+	for _, key := range settingKeys() {
+		if tree.Has(key) && !containsString(projectSettingKeys, key) {
+			ignored = append(ignored, key)
+		}
+	}
+
+	return ignored
+}
+
+// mergeSettings overrides the values of base with the values set in
+// override. Templates and model aliases are merged by name.
+func mergeSettings(base *Settings, override *Settings) {
+	mergeString(&base.Setting.Editor, override.Setting.Editor)
+	mergeString(&base.Setting.ChatLogDir, override.Setting.ChatLogDir)
+	mergeString(&base.Setting.ChatLogFormat, override.Setting.ChatLogFormat)
+	mergeString(&base.Setting.ChatLogSort, override.Setting.ChatLogSort)
+	if override.Setting.ChatLogRecursive != nil {
+		base.Setting.ChatLogRecursive = override.Setting.ChatLogRecursive
+	}
+
+	mergeString(&base.Chat.Model, override.Chat.Model)
+	mergeString(&base.Chat.DefaultTemplate, override.Chat.DefaultTemplate)
+	mergeString(&base.Chat.FileNameFormat, override.Chat.FileNameFormat)
+
+	mergeTemplates(&base.Chat.Templates, override.Chat.Templates)
+
+	if override.Search.Semantic {
+		base.Search.Semantic = true
+	}
+	mergeString(&base.Search.EmbeddingModel, override.Search.EmbeddingModel)
+
+	mergeString(&base.Output.Render, override.Output.Render)
+	mergeString(&base.Output.Theme, override.Output.Theme)
+
+	for name, alias := range override.Models {
+		if base.Models == nil {
+			base.Models = map[string]ModelAlias{}
+		}
+		base.Models[name] = alias
+	}
+}
+
+func mergeString(base *string, override string) {
+	if override != "" {
+		*base = override
+	}
+}
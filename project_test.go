@@ -0,0 +1,130 @@
+package oax
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindProject(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "repo", "pkg", "sub")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatalf("Error: Cannot create dir: %v", err)
+	}
+
+	project, err := FindProject(sub)
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if project != nil && filepath.HasPrefix(project.Root, root) {
+		t.Fatalf("Expected no project but got %+v", project)
+	}
+
+	repo := filepath.Join(root, "repo")
+	if err := os.WriteFile(filepath.Join(repo, ProjectFileName), []byte(`[setting]
+  chatLogDir = "docs/chat"
+
+[chat]
+  model = "gpt-4"
+  defaultTemplate = "reviewer"
+
+  [[chat.templates]]
+    name = "reviewer"
+`), 0644); err != nil {
+		t.Fatalf("Error: Cannot write file: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(repo, "pkg", ProjectDirName), 0755); err != nil {
+		t.Fatalf("Error: Cannot create dir: %v", err)
+	}
+
+	testCases := []struct {
+		dir                string
+		expectedRoot       string
+		expectedChatLogDir string
+		expectedModel      string
+	}{
+		{sub, filepath.Join(repo, "pkg"), filepath.Join(repo, "pkg", ProjectDirName, "chat-log"), "gpt-3.5-turbo"},
+		{repo, repo, filepath.Join(repo, "docs", "chat"), "gpt-4"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.dir, func(t *testing.T) {
+			project, err := FindProject(tc.dir)
+			if err != nil {
+				t.Fatalf("Error: Return err func: %v", err)
+			}
+			if project == nil || project.Root != tc.expectedRoot {
+				t.Fatalf("Expected project at %q but got %+v", tc.expectedRoot, project)
+			}
+
+			projectSetting, _, err := project.loadSetting()
+			if err != nil {
+				t.Fatalf("Error: Return err func: %v", err)
+			}
+
+			setting := &Settings{
+				Setting: Setting{Editor: "vim", ChatLogDir: "/global/chat-log"},
+				Chat: Chat{
+					Model:     "gpt-3.5-turbo",
+					Templates: []ChatTemplate{{Name: "reviewer", Messages: []Message{{Role: "system", Content: "global"}}}},
+				},
+			}
+			mergeProjectSettings(setting, projectSetting)
+
+			if setting.Setting.ChatLogDir != tc.expectedChatLogDir {
+				t.Errorf("Expected %q but got %q", tc.expectedChatLogDir, setting.Setting.ChatLogDir)
+			}
+			if setting.Chat.Model != tc.expectedModel {
+				t.Errorf("Expected %q but got %q", tc.expectedModel, setting.Chat.Model)
+			}
+			if setting.Setting.Editor != "vim" {
+				t.Errorf("Expected the global editor to be kept but got %q", setting.Setting.Editor)
+			}
+			if len(setting.Chat.Templates) != 1 {
+				t.Errorf("Expected templates to be merged by name but got %d", len(setting.Chat.Templates))
+			}
+		})
+	}
+}
+
+func TestIgnoredProjectSettings(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, ProjectFileName), []byte(`[setting]
+  editor = "./run-me.sh"
+  chatLogDir = "chat"
+
+[chat]
+  model = "gpt-4"
+
+[search]
+  semantic = true
+`), 0644); err != nil {
+		t.Fatalf("Error: Cannot write file: %v", err)
+	}
+
+	project, err := FindProject(root)
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	projectSetting, projectTree, err := project.loadSetting()
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+
+	setting := &Settings{Setting: Setting{Editor: "vim"}}
+	mergeProjectSettings(setting, projectSetting)
+
+	if setting.Setting.Editor != "vim" || setting.Search.Semantic {
+		t.Errorf("Expected the editor and semantic search not to be set by a project but got %+v", setting)
+	}
+	if setting.Chat.Model != "gpt-4" {
+		t.Errorf("Expected %q but got %q", "gpt-4", setting.Chat.Model)
+	}
+
+	expected := []string{"setting.editor", "search.semantic"}
+	if ignored := ignoredProjectSettings(projectTree); !reflect.DeepEqual(ignored, expected) {
+		t.Errorf("Expected %q but got %q", expected, ignored)
+	}
+}
@@ -0,0 +1,338 @@
+package oax
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// IndexesDirName holds the search and vector indexes, one directory
+	// per chat log directory. See IndexDir.
+	IndexesDirName      = "indexes"
+	SearchIndexFileName = "search-index.json"
+	searchIndexVersion  = 3
+)
+
+// IndexDir returns the directory in dir holding the indexes of the chat
+// logs in chatLogDir. The global and the project chat log directories have
+// separate indexes, so that updating the index of one does not drop the
+// entries of the others.
+func IndexDir(dir string, chatLogDir string) string {
+	sum := sha256.Sum256([]byte(filepath.Clean(chatLogDir)))
+
+	return filepath.Join(dir, IndexesDirName, hex.EncodeToString(sum[:8]))
+}
+
+// SearchIndex caches the parsed messages of every chat log keyed by file
+// path. Entries are refreshed only when the modification time or size of
+// the file changes, so searching thousands of logs does not parse them
+// all each time.
+type SearchIndex struct {
+	Version int                         `json:"version"`
+	Entries map[string]SearchIndexEntry `json:"entries"`
+
+	path string
+}
+
+type SearchIndexEntry struct {
+	ModTime  time.Time     `json:"modTime"`
+	Size     int64         `json:"size"`
+	Model    string        `json:"model,omitempty"`
+	Tags     []string      `json:"tags,omitempty"`
+	Messages []ChatMessage `json:"messages"`
+}
+
+type SearchQuery struct {
+	Pattern string
+	// Regex treats Pattern as a regular expression. Otherwise Pattern is
+	// split into words and "quoted phrases" that must all appear in the
+	// conversation, ignoring case.
+	Regex bool
+	Roles []string
+	Since time.Time
+	Until time.Time
+}
+
+type SearchResult struct {
+	FilePath string
+	ModTime  time.Time
+	Matches  []SearchMatch
+}
+
+type SearchMatch struct {
+	Role         string
+	MessageIndex int
+	Snippet      string
+}
+
+func OpenSearchIndex(path string) (*SearchIndex, error) {
+	index := &SearchIndex{
+		Version: searchIndexVersion,
+		Entries: map[string]SearchIndexEntry{},
+		path:    path,
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var stored SearchIndex
+	if err := json.Unmarshal(data, &stored); err != nil || stored.Version != searchIndexVersion {
+		// A broken or outdated index is rebuilt from the chat logs.
+		return index, nil
+	}
+	if stored.Entries != nil {
+		index.Entries = stored.Entries
+	}
+
+	return index, nil
+}
+
+// Update re-reads chat logs that changed since they were indexed and drops
+// entries for files that no longer exist. It reports whether the index
+// changed.
+func (idx *SearchIndex) Update(files []FileInfo) (bool, error) {
+	changed := false
+	seen := map[string]bool{}
+
+	for _, file := range files {
+		if !IsChatLogFile(file.FileFullPath) {
+			continue
+		}
+
+		info, err := os.Stat(file.FileFullPath)
+		if os.IsNotExist(err) {
+			// The chat log was removed or renamed after it was listed, and
+			// its entry is dropped below.
+			continue
+		}
+		if err != nil {
+			return changed, err
+		}
+		seen[file.FileFullPath] = true
+
+		entry, ok := idx.Entries[file.FileFullPath]
+		if ok && entry.ModTime.Equal(info.ModTime()) && entry.Size == info.Size() {
+			continue
+		}
+
+		chatLog, err := LoadChatLog(file.FileFullPath)
+		if err != nil {
+			// Keep searching the other logs; a log that is being edited by
+			// hand may be temporarily invalid.
+			fmt.Fprintf(os.Stderr, "skip index: %s\n", err)
+			delete(idx.Entries, file.FileFullPath)
+			changed = true
+
+			continue
+		}
+
+		idx.Entries[file.FileFullPath] = SearchIndexEntry{
+			ModTime:  info.ModTime(),
+			Size:     info.Size(),
+			Model:    chatLog.ChatLogToml.Model,
+			Tags:     chatLog.ChatLogToml.Tags,
+			Messages: chatLog.ChatLogToml.Messages,
+		}
+		changed = true
+	}
+
+	for path := range idx.Entries {
+		if !seen[path] {
+			delete(idx.Entries, path)
+			changed = true
+		}
+	}
+
+	return changed, nil
+}
+
+func (idx *SearchIndex) Save() error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(idx.path), 0755); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(idx.path, data, 0644, false)
+}
+
+// UpdateSearchIndex lists the chat logs in chatLogDir, including archived
+// ones, refreshes the index stored at indexPath and saves it when anything
+// changed.
+func UpdateSearchIndex(indexPath string, chatLogDir string) (*SearchIndex, error) {
+	index, err := OpenSearchIndex(indexPath)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := ListFiles(chatLogDir, ListOption{Recursive: true, IncludeArchived: true})
+	if err != nil {
+		return nil, err
+	}
+
+	changed, err := index.Update(files)
+	if err != nil {
+		return nil, err
+	}
+
+	if changed {
+		if err := index.Save(); err != nil {
+			return nil, err
+		}
+	}
+
+	return index, nil
+}
+
+// Search returns the conversations matching the query, most recently
+// modified first.
+func (idx *SearchIndex) Search(query SearchQuery) ([]SearchResult, error) {
+	patterns, err := query.compile()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []SearchResult
+	for path, entry := range idx.Entries {
+		if !query.Since.IsZero() && entry.ModTime.Before(query.Since) {
+			continue
+		}
+		if !query.Until.IsZero() && !entry.ModTime.Before(query.Until) {
+			continue
+		}
+
+		result := SearchResult{FilePath: path, ModTime: entry.ModTime}
+		found := make([]bool, len(patterns))
+
+		for i, message := range entry.Messages {
+			if len(query.Roles) > 0 && !containsString(query.Roles, message.Role) {
+				continue
+			}
+
+			var loc []int
+			for j, pattern := range patterns {
+				if l := pattern.FindStringIndex(message.Content); l != nil {
+					found[j] = true
+					if loc == nil {
+						loc = l
+					}
+				}
+			}
+
+			if loc != nil {
+				result.Matches = append(result.Matches, SearchMatch{
+					Role:         message.Role,
+					MessageIndex: i,
+					Snippet:      snippet(message.Content, loc[0], loc[1]),
+				})
+			}
+		}
+
+		if len(result.Matches) > 0 && allTrue(found) {
+			results = append(results, result)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].ModTime.Equal(results[j].ModTime) {
+			return results[i].FilePath > results[j].FilePath
+		}
+
+		return results[i].ModTime.After(results[j].ModTime)
+	})
+
+	return results, nil
+}
+
+func (q SearchQuery) compile() ([]*regexp.Regexp, error) {
+	if q.Regex {
+		pattern, err := regexp.Compile(q.Pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*regexp.Regexp{pattern}, nil
+	}
+
+	var patterns []*regexp.Regexp
+	for _, term := range splitSearchTerms(q.Pattern) {
+		patterns = append(patterns, regexp.MustCompile("(?i)"+regexp.QuoteMeta(term)))
+	}
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("empty search query")
+	}
+
+	return patterns, nil
+}
+
+// splitSearchTerms splits a query into words, keeping "quoted phrases"
+// together.
+func splitSearchTerms(query string) []string {
+	var terms []string
+
+	for i, part := range strings.Split(query, `"`) {
+		if i%2 == 1 {
+			if phrase := strings.TrimSpace(part); phrase != "" {
+				terms = append(terms, phrase)
+			}
+		} else {
+			terms = append(terms, strings.Fields(part)...)
+		}
+	}
+
+	return terms
+}
+
+const snippetContext = 40
+
+// snippet returns the matched text with some surrounding context on a
+// single line.
+func snippet(content string, start int, end int) string {
+	from := start
+	for n := 0; from > 0 && n < snippetContext; n++ {
+		_, size := utf8.DecodeLastRuneInString(content[:from])
+		from -= size
+	}
+	to := end
+	for n := 0; to < len(content) && n < snippetContext; n++ {
+		_, size := utf8.DecodeRuneInString(content[to:])
+		to += size
+	}
+
+	s := strings.Join(strings.Fields(content[from:to]), " ")
+	if from > 0 {
+		s = "..." + s
+	}
+	if to < len(content) {
+		s = s + "..."
+	}
+
+	return s
+}
+
+func allTrue(values []bool) bool {
+	for _, v := range values {
+		if !v {
+			return false
+		}
+	}
+
+	return true
+}
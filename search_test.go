@@ -0,0 +1,122 @@
+package oax
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSplitSearchTerms(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected []string
+	}{
+		{"grpc deadlines", []string{"grpc", "deadlines"}},
+		{`"context deadline" grpc`, []string{"context deadline", "grpc"}},
+		{`  "unterminated phrase`, []string{"unterminated phrase"}},
+		{"", nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			result := splitSearchTerms(tc.input)
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("Expected %q but got %q", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestSearchIndexSearch(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2023, 4, d, 12, 0, 0, 0, time.UTC) }
+
+	index, err := OpenSearchIndex(filepath.Join(t.TempDir(), SearchIndexFileName))
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	index.Entries = map[string]SearchIndexEntry{
+		"grpc.toml": {ModTime: day(1), Messages: []ChatMessage{
+			{Role: "user", Content: "How do gRPC deadlines propagate?"},
+			{Role: "assistant", Content: "The context deadline is sent as grpc-timeout."},
+		}},
+		"http.toml": {ModTime: day(2), Messages: []ChatMessage{
+			{Role: "user", Content: "How do HTTP deadlines work?"},
+		}},
+	}
+
+	testCases := []struct {
+		name     string
+		query    SearchQuery
+		expected []string
+	}{
+		{"terms across messages", SearchQuery{Pattern: "grpc-timeout deadlines"}, []string{"grpc.toml"}},
+		{"newest first", SearchQuery{Pattern: "DEADLINES"}, []string{"http.toml", "grpc.toml"}},
+		{"phrase", SearchQuery{Pattern: `"context deadline"`}, []string{"grpc.toml"}},
+		{"regex", SearchQuery{Pattern: `g?RPC|HTTP`, Regex: true}, []string{"http.toml", "grpc.toml"}},
+		{"role", SearchQuery{Pattern: "grpc-timeout", Roles: []string{"user"}}, nil},
+		{"since", SearchQuery{Pattern: "deadlines", Since: day(2)}, []string{"http.toml"}},
+		{"until", SearchQuery{Pattern: "deadlines", Until: day(2)}, []string{"grpc.toml"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			results, err := index.Search(tc.query)
+			if err != nil {
+				t.Fatalf("Error: Return err func: %v", err)
+			}
+
+			var paths []string
+			for _, result := range results {
+				paths = append(paths, result.FilePath)
+			}
+			if !reflect.DeepEqual(paths, tc.expected) {
+				t.Errorf("Expected %q but got %q", tc.expected, paths)
+			}
+		})
+	}
+}
+
+func TestIndexDir(t *testing.T) {
+	dir := t.TempDir()
+	global := IndexDir(dir, "/home/me/.local/share/oax/chat-log")
+	project := IndexDir(dir, "/src/repo/.oax/chat-log")
+
+	if global == project {
+		t.Errorf("Expected separate indexes but got %q for both", global)
+	}
+	if same := IndexDir(dir, "/src/repo/.oax/chat-log/"); same != project {
+		t.Errorf("Expected %q but got %q", project, same)
+	}
+	if filepath.Dir(filepath.Dir(global)) != dir {
+		t.Errorf("Expected %q to be in %q", global, dir)
+	}
+}
+
+func TestSearchIndexUpdateSkipsRemovedFile(t *testing.T) {
+	dir := t.TempDir()
+	kept := filepath.Join(dir, "kept.toml")
+	removed := filepath.Join(dir, "removed.toml")
+	if err := os.WriteFile(kept, []byte("[[messages]]\n  role = \"user\"\n  content = \"hi\"\n"), 0644); err != nil {
+		t.Fatalf("Error: Cannot write file: %v", err)
+	}
+
+	index, err := OpenSearchIndex(filepath.Join(dir, SearchIndexFileName))
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	index.Entries[removed] = SearchIndexEntry{}
+
+	// removed.toml was listed but deleted before it was indexed.
+	changed, err := index.Update([]FileInfo{{FileFullPath: kept}, {FileFullPath: removed}})
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if _, ok := index.Entries[removed]; !changed || ok {
+		t.Errorf("Expected the entry of the removed file to be dropped but got %+v", index.Entries)
+	}
+	if _, ok := index.Entries[kept]; !ok {
+		t.Errorf("Expected %s to be indexed", kept)
+	}
+}
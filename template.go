@@ -0,0 +1,316 @@
+package oax
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// TemplateParam declares a variable of a chat template.
+type TemplateParam struct {
+	Name        string `toml:"name"`
+	Description string `toml:"description"`
+	Default     string `toml:"default"`
+}
+
+// clipboardField is the template field holding the clipboard content. The
+// clipboard is only read when a template uses it.
+const clipboardField = "Clipboard"
+
+type RenderTemplateOption struct {
+	// Vars are the values given with --var.
+	Vars map[string]string
+	// Prompt asks for the value of a variable that has neither a value in
+	// Vars nor a default. When nil, a missing value is an error.
+	Prompt func(param TemplateParam) (string, error)
+	// Warn, when set, is called with problems that do not stop rendering.
+	Warn func(message string)
+}
+
+// Render executes the message contents of the template with text/template.
+// Variables are the declared params and any other field the contents use,
+// such as {{.Lang}}. The functions include, which reads a file, and env
+// are available, and {{.Clipboard}} is the clipboard content. In a
+// template from a project, include only reads files in the project and env
+// is an error. A template
+// without params or Expand is returned as it is, so that messages written
+// before templates were rendered may contain {{ literally. Vars are an
+// error for such a template, and {{ in it is a warning, since either
+// suggests that rendering was expected.
+func (t ChatTemplate) Render(opt *RenderTemplateOption) ([]Message, error) {
+	if len(t.Params) == 0 && !t.Expand {
+		if len(opt.Vars) > 0 {
+			return nil, fmt.Errorf("template %s is not rendered, so --var has no effect. Declare params or set expand = true in it", t.Name)
+		}
+
+		for _, message := range t.Messages {
+			if strings.Contains(message.Content, "{{") && opt.Warn != nil {
+				opt.Warn(fmt.Sprintf("template %s is sent as it is, including {{. Declare params or set expand = true in it to render it", t.Name))
+
+				break
+			}
+		}
+
+		return append([]Message(nil), t.Messages...), nil
+	}
+
+	var templates []*template.Template
+	var fields []string
+
+	for i, message := range t.Messages {
+		tmpl, err := template.New(fmt.Sprintf("%s[%d]", t.Name, i)).
+			Funcs(t.funcs()).
+			Option("missingkey=error").
+			Parse(message.Content)
+		if err != nil {
+			return nil, fmt.Errorf("template %s: %w", t.Name, err)
+		}
+
+		templates = append(templates, tmpl)
+		fields = appendTemplateFields(fields, tmpl.Tree.Root)
+	}
+
+	params := append([]TemplateParam(nil), t.Params...)
+	for _, field := range fields {
+		if field != clipboardField && !hasTemplateParam(params, field) {
+			params = append(params, TemplateParam{Name: field})
+		}
+	}
+
+	data := map[string]interface{}{}
+	for name, value := range opt.Vars {
+		data[name] = value
+	}
+
+	for _, param := range params {
+		if _, ok := data[param.Name]; ok {
+			continue
+		}
+
+		switch {
+		case param.Default != "":
+			data[param.Name] = param.Default
+		case opt.Prompt != nil:
+			value, err := opt.Prompt(param)
+			if err != nil {
+				return nil, err
+			}
+			data[param.Name] = value
+		default:
+			return nil, fmt.Errorf("template %s: no value for %s. Use --var %s=value", t.Name, param.Name, param.Name)
+		}
+	}
+
+	if containsString(fields, clipboardField) {
+		if _, ok := data[clipboardField]; !ok {
+			clipboard, err := ReadClipboard()
+			if err != nil {
+				return nil, fmt.Errorf("template %s: %w", t.Name, err)
+			}
+			data[clipboardField] = clipboard
+		}
+	}
+
+	var messages []Message
+	for i, tmpl := range templates {
+		var buf bytes.Buffer
+		err := tmpl.Execute(&buf, data)
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, Message{
+			Role:    t.Messages[i].Role,
+			Content: buf.String(),
+		})
+	}
+
+	return messages, nil
+}
+
+var templateFuncs = template.FuncMap{
+	"include": includeFile,
+	"env":     os.Getenv,
+}
+
+func (t ChatTemplate) funcs() template.FuncMap {
+	if t.projectRoot == "" {
+		return templateFuncs
+	}
+
+	return template.FuncMap{
+		"include": func(path string) (string, error) {
+			return includeProjectFile(t.projectRoot, path)
+		},
+		"env": func(name string) (string, error) {
+			return "", fmt.Errorf("env is not available in the templates of a project. Use --var instead")
+		},
+	}
+}
+
+// includeProjectFile reads path like includeFile when it is in the project
+// at root, following symbolic links.
+func includeProjectFile(root string, path string) (string, error) {
+	path, err := replaceTildeWithHomedir(path)
+	if err != nil {
+		return "", err
+	}
+
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+	resolvedPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	resolvedPath, err = filepath.Abs(resolvedPath)
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(resolvedRoot, resolvedPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the project %s; the templates of a project can only include its files", path, root)
+	}
+
+	return includeFile(resolvedPath)
+}
+
+func includeFile(path string) (string, error) {
+	path, err := replaceTildeWithHomedir(path)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(data), "\n"), nil
+}
+
+func hasTemplateParam(params []TemplateParam, name string) bool {
+	for _, param := range params {
+		if param.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// appendTemplateFields appends the names of the top-level fields, like Lang
+// in {{.Lang}}, used in node and not yet in fields.
+func appendTemplateFields(fields []string, node parse.Node) []string {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return fields
+		}
+		for _, child := range n.Nodes {
+			fields = appendTemplateFields(fields, child)
+		}
+	case *parse.ActionNode:
+		fields = appendTemplateFields(fields, n.Pipe)
+	case *parse.IfNode:
+		fields = appendBranchFields(fields, &n.BranchNode)
+	case *parse.RangeNode:
+		fields = appendBranchFields(fields, &n.BranchNode)
+	case *parse.WithNode:
+		fields = appendBranchFields(fields, &n.BranchNode)
+	case *parse.TemplateNode:
+		fields = appendTemplateFields(fields, n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return fields
+		}
+		for _, cmd := range n.Cmds {
+			fields = appendTemplateFields(fields, cmd)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			fields = appendTemplateFields(fields, arg)
+		}
+	case *parse.ChainNode:
+		fields = appendTemplateFields(fields, n.Node)
+	case *parse.FieldNode:
+		if len(n.Ident) > 0 && !containsString(fields, n.Ident[0]) {
+			fields = append(fields, n.Ident[0])
+		}
+	}
+
+	return fields
+}
+
+// appendBranchFields only looks at the pipeline of a branch, since the dot
+// changes inside range and with.
+func appendBranchFields(fields []string, n *parse.BranchNode) []string {
+	fields = appendTemplateFields(fields, n.Pipe)
+	if n.NodeType == parse.NodeIf {
+		fields = appendTemplateFields(fields, n.List)
+		fields = appendTemplateFields(fields, n.ElseList)
+	}
+
+	return fields
+}
+
+const (
+	// TemplateModeNew starts a conversation with all template messages.
+	TemplateModeNew = "new"
+	// TemplateModeSystem replaces the system messages of a conversation
+	// with those of the template, inserted at its beginning.
+	TemplateModeSystem = "system"
+	// TemplateModeAppend appends the other messages of the template as the
+	// next turn of a conversation.
+	TemplateModeAppend = "append"
+)
+
+// ApplyTemplate adds the rendered messages of the template name to the
+// conversation according to mode and records it in the chat log.
+func (c *ChatLog) ApplyTemplate(name string, messages []Message, mode string) error {
+	var systemMessages, otherMessages []ChatMessage
+	for _, message := range messages {
+		if message.Role == "system" {
+			systemMessages = append(systemMessages, ChatMessage(message))
+		} else {
+			otherMessages = append(otherMessages, ChatMessage(message))
+		}
+	}
+
+	switch mode {
+	case TemplateModeNew:
+		for _, message := range messages {
+			c.AddChatMessage(ChatMessage(message))
+		}
+	case TemplateModeSystem:
+		if len(systemMessages) == 0 {
+			return fmt.Errorf("template %s has no system messages. Use --template-mode %s to append its messages", name, TemplateModeAppend)
+		}
+
+		chatMessages := systemMessages
+		for _, message := range c.ChatLogToml.Messages {
+			if message.Role != "system" {
+				chatMessages = append(chatMessages, message)
+			}
+		}
+		c.ChatLogToml.Messages = chatMessages
+	case TemplateModeAppend:
+		if len(otherMessages) == 0 {
+			return fmt.Errorf("template %s has only system messages. Use --template-mode %s to apply them", name, TemplateModeSystem)
+		}
+
+		c.ChatLogToml.Messages = append(c.ChatLogToml.Messages, otherMessages...)
+	default:
+		return fmt.Errorf("unknown template mode %q (supported: %s, %s)", mode, TemplateModeSystem, TemplateModeAppend)
+	}
+
+	c.ChatLogToml.Templates = append(c.ChatLogToml.Templates, name+":"+mode)
+
+	return nil
+}
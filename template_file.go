@@ -0,0 +1,343 @@
+package oax
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+)
+
+// TemplatesDirName is the directory of template files in the config
+// directory and in the .oax directory of a project.
+const TemplatesDirName = "templates"
+
+const templateFileExtension = ".toml"
+
+const templateFileSkeleton = `description = ""
+# extends = "base"
+# include = ["style-guide"]
+
+# [[params]]
+#   name = "Lang"
+#   description = "Programming language"
+#   default = "Go"
+
+[[messages]]
+  role = "system"
+  content = """
+You are a helpful assistant.
+"""
+`
+
+// LoadTemplateDir loads the *.toml template files in dir, sorted by name.
+// The name of a template defaults to its file name. A missing directory has
+// no templates. A file that cannot be loaded is skipped and its error is
+// returned with the others, so that one broken file does not hide the rest.
+func LoadTemplateDir(dir string) ([]ChatTemplate, []error) {
+	filePaths, _ := filepath.Glob(filepath.Join(dir, "*"+templateFileExtension))
+	sort.Strings(filePaths)
+
+	var templates []ChatTemplate
+	var errs []error
+	for _, filePath := range filePaths {
+		template, err := LoadTemplateFile(filePath)
+		if err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+		templates = append(templates, template)
+	}
+
+	return templates, errs
+}
+
+func LoadTemplateFile(filePath string) (ChatTemplate, error) {
+	tree, err := toml.LoadFile(filePath)
+	if err != nil {
+		return ChatTemplate{}, fmt.Errorf("error load template %s: %w", filePath, err)
+	}
+
+	var template ChatTemplate
+	err = tree.Unmarshal(&template)
+	if err != nil {
+		return ChatTemplate{}, fmt.Errorf("error load template %s: %w", filePath, err)
+	}
+
+	if template.Name == "" {
+		template.Name = strings.TrimSuffix(filepath.Base(filePath), templateFileExtension)
+	}
+	template.FilePath = filePath
+
+	return template, nil
+}
+
+// TemplateDir returns the global template directory, or with project the
+// one of the project, which must have a .oax directory.
+func (c *Config) TemplateDir(project bool) (string, error) {
+	if !project {
+		return filepath.Join(c.ConfigDir, TemplatesDirName), nil
+	}
+
+	if c.Project == nil {
+		return "", fmt.Errorf("no project found: create a %s directory in the project root", ProjectDirName)
+	}
+
+	projectDir := filepath.Join(c.Project.Root, ProjectDirName)
+	if info, err := os.Stat(projectDir); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("project %s has no %s directory", c.Project.Root, ProjectDirName)
+	}
+
+	return filepath.Join(projectDir, TemplatesDirName), nil
+}
+
+// FindTemplateFile returns the file of the template directories, the one of
+// the project first, named after the template, or empty when there is none.
+// It finds the files of templates that were skipped because they cannot be
+// loaded.
+func (c *Config) FindTemplateFile(name string) string {
+	if name == "" || name != filepath.Base(name) {
+		return ""
+	}
+
+	var dirs []string
+	if c.Project != nil {
+		dirs = append(dirs, filepath.Join(c.Project.Root, ProjectDirName, TemplatesDirName))
+	}
+	dirs = append(dirs, filepath.Join(c.ConfigDir, TemplatesDirName))
+
+	for _, dir := range dirs {
+		filePath := filepath.Join(dir, name+templateFileExtension)
+		if fileExists(filePath) {
+			return filePath
+		}
+	}
+
+	return ""
+}
+
+// NewTemplateFile creates a template file with a skeleton in dir and
+// returns its path.
+func NewTemplateFile(dir string, name string) (string, error) {
+	if name == "" || name != sanitizeFileName(name) || name != filepath.Base(name) {
+		return "", fmt.Errorf("invalid template name %q", name)
+	}
+
+	filePath := filepath.Join(dir, name+templateFileExtension)
+	if fileExists(filePath) {
+		return "", fmt.Errorf("template file %s already exists", filePath)
+	}
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	return filePath, writeFileAtomic(filePath, []byte(templateFileSkeleton), 0644, false)
+}
+
+// FindTemplate returns the template with name, or an error suggesting a
+// similar name. A template whose extends or include cannot be resolved is
+// returned with that error.
+func FindTemplate(templates []ChatTemplate, name string) (*ChatTemplate, error) {
+	var names []string
+	for i := range templates {
+		if templates[i].Name == name {
+			return &templates[i], templates[i].err
+		}
+		names = append(names, templates[i].Name)
+	}
+
+	message := fmt.Sprintf("unknown template %s", name)
+	if suggestion := suggest(name, names); suggestion != "" {
+		message += fmt.Sprintf(" (did you mean %s?)", suggestion)
+	} else if len(names) > 0 {
+		message += fmt.Sprintf(" (available: %s)", strings.Join(names, ", "))
+	}
+
+	return nil, fmt.Errorf("%s", message)
+}
+
+// mergeTemplates replaces the templates of base with the templates of the
+// same name in override and appends the others.
+func mergeTemplates(base *[]ChatTemplate, override []ChatTemplate) {
+	for _, template := range override {
+		replaced := false
+		for i := range *base {
+			if (*base)[i].Name == template.Name {
+				(*base)[i] = template
+				replaced = true
+			}
+		}
+		if !replaced {
+			*base = append(*base, template)
+		}
+	}
+}
+
+func setTemplateFilePath(templates []ChatTemplate, filePath string) {
+	for i := range templates {
+		templates[i].FilePath = filePath
+	}
+}
+
+func setTemplateProjectRoot(templates []ChatTemplate, root string) {
+	for i := range templates {
+		templates[i].projectRoot = root
+	}
+}
+
+// TemplateError is an error in the extends or include of a template.
+type TemplateError struct {
+	Name     string
+	FilePath string
+	Err      error
+}
+
+func (e *TemplateError) Error() string {
+	return fmt.Sprintf("template %s (%s): %v", e.Name, e.FilePath, e.Err)
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// ResolveTemplates flattens extends and include so that each template has
+// the params and messages of the template it extends, then those of the
+// templates it includes in order, then its own. A template reached through
+// several of them, such as one included directly and through another
+// template, contributes once, at its first place. A param of the same name
+// replaces an inherited one, and the description is inherited when empty.
+//
+// A template that extends or includes itself, directly or not, or an
+// unknown template, is an error. Such a template is kept unresolved with
+// its error, which FindTemplate returns, and the errors are returned too.
+func ResolveTemplates(templates []ChatTemplate) ([]ChatTemplate, []error) {
+	r := &templateResolver{
+		templates: templates,
+		resolved:  map[string]ChatTemplate{},
+		lineages:  map[string][]string{},
+	}
+
+	resolvedTemplates := make([]ChatTemplate, len(templates))
+	var errs []error
+	for i := range templates {
+		template, err := r.resolve(templates[i])
+		if err != nil {
+			template = templates[i]
+			template.err = err
+			errs = append(errs, err)
+		}
+		resolvedTemplates[i] = template
+	}
+
+	return resolvedTemplates, errs
+}
+
+type templateResolver struct {
+	templates []ChatTemplate
+	resolved  map[string]ChatTemplate
+	// lineages maps a resolved template to the names of the templates its
+	// params and messages come from, in order and each once, ending with
+	// itself.
+	lineages map[string][]string
+	// stack is the chain of templates being resolved, to detect cycles.
+	stack []string
+}
+
+func (r *templateResolver) resolve(template ChatTemplate) (ChatTemplate, error) {
+	if resolved, ok := r.resolved[template.Name]; ok {
+		return resolved, nil
+	}
+
+	for i, name := range r.stack {
+		if name == template.Name {
+			cycle := append(append([]string(nil), r.stack[i:]...), template.Name)
+
+			return ChatTemplate{}, fmt.Errorf("cycle in extends or include: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	r.stack = append(r.stack, template.Name)
+	defer func() {
+		r.stack = r.stack[:len(r.stack)-1]
+	}()
+
+	var parents []string
+	if template.Extends != "" {
+		parents = append(parents, template.Extends)
+	}
+	parents = append(parents, template.Include...)
+
+	var lineage []string
+	for _, name := range parents {
+		parent, err := r.resolveName(name)
+		if err != nil {
+			var templateErr *TemplateError
+			if errors.As(err, &templateErr) {
+				return ChatTemplate{}, err
+			}
+
+			return ChatTemplate{}, &TemplateError{Name: template.Name, FilePath: template.FilePath, Err: err}
+		}
+
+		if name == template.Extends && template.Description == "" {
+			template.Description = parent.Description
+		}
+		for _, ancestor := range r.lineages[parent.Name] {
+			if !containsString(lineage, ancestor) {
+				lineage = append(lineage, ancestor)
+			}
+		}
+	}
+
+	var params []TemplateParam
+	var messages []Message
+	for _, name := range lineage {
+		ancestor, _ := FindTemplate(r.templates, name)
+		template.Expand = template.Expand || ancestor.Expand
+		if template.projectRoot == "" {
+			template.projectRoot = ancestor.projectRoot
+		}
+		params = mergeTemplateParams(params, ancestor.Params)
+		messages = append(messages, ancestor.Messages...)
+	}
+
+	template.Params = mergeTemplateParams(params, template.Params)
+	template.Messages = append(messages, template.Messages...)
+	r.resolved[template.Name] = template
+	r.lineages[template.Name] = append(lineage, template.Name)
+
+	return template, nil
+}
+
+func (r *templateResolver) resolveName(name string) (ChatTemplate, error) {
+	template, err := FindTemplate(r.templates, name)
+	if err != nil {
+		return ChatTemplate{}, err
+	}
+
+	return r.resolve(*template)
+}
+
+func mergeTemplateParams(base []TemplateParam, override []TemplateParam) []TemplateParam {
+	merged := append([]TemplateParam(nil), base...)
+	for _, param := range override {
+		replaced := false
+		for i := range merged {
+			if merged[i].Name == param.Name {
+				merged[i] = param
+				replaced = true
+			}
+		}
+		if !replaced {
+			merged = append(merged, param)
+		}
+	}
+
+	return merged
+}
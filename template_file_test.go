@@ -0,0 +1,205 @@
+package oax
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadTemplateDir(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"review.toml": `description = "Review code"
+
+[[messages]]
+  role = "system"
+  content = "You review code."
+`,
+		"named.toml": `name = "translator"
+
+[[messages]]
+  role = "system"
+  content = "You translate."
+`,
+		"notes.txt":   "not a template",
+		"broken.toml": "messages = [",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Error: Cannot write file: %v", err)
+		}
+	}
+
+	templates, errs := LoadTemplateDir(dir)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "broken.toml") {
+		t.Fatalf("Expected an error for broken.toml but got %v", errs)
+	}
+
+	var names []string
+	for _, template := range templates {
+		names = append(names, template.Name)
+	}
+	if strings.Join(names, ",") != "translator,review" {
+		t.Fatalf("Expected %q but got %q", "translator,review", strings.Join(names, ","))
+	}
+	if templates[1].Description != "Review code" {
+		t.Errorf("Expected %q but got %q", "Review code", templates[1].Description)
+	}
+	if expected := filepath.Join(dir, "review.toml"); templates[1].FilePath != expected {
+		t.Errorf("Expected %q but got %q", expected, templates[1].FilePath)
+	}
+
+	base := []ChatTemplate{{Name: "review", FilePath: "settings.toml"}, {Name: "other"}}
+	mergeTemplates(&base, templates)
+	if len(base) != 3 || base[0].FilePath != filepath.Join(dir, "review.toml") || base[2].Name != "translator" {
+		t.Errorf("Unexpected merged templates %+v", base)
+	}
+
+	templates, errs = LoadTemplateDir(filepath.Join(dir, "missing"))
+	if len(errs) != 0 || len(templates) != 0 {
+		t.Errorf("Expected no templates but got %+v, %v", templates, errs)
+	}
+}
+
+func TestFindTemplate(t *testing.T) {
+	templates := []ChatTemplate{{Name: "reviewer"}, {Name: "translator"}}
+
+	testCases := []struct {
+		name          string
+		templateName  string
+		expectedError string
+	}{
+		{"found", "translator", ""},
+		{"suggestion", "reviwer", "unknown template reviwer (did you mean reviewer?)"},
+		{"available", "summary", "unknown template summary (available: reviewer, translator)"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			template, err := FindTemplate(templates, tc.templateName)
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Fatalf("Error: Return err func: %v", err)
+				}
+				if template.Name != tc.templateName {
+					t.Errorf("Expected %q but got %q", tc.templateName, template.Name)
+				}
+
+				return
+			}
+			if err == nil || err.Error() != tc.expectedError {
+				t.Errorf("Expected %q but got %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestNewTemplateFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), TemplatesDirName)
+
+	filePath, err := NewTemplateFile(dir, "review")
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+
+	template, err := LoadTemplateFile(filePath)
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if template.Name != "review" || len(template.Messages) != 1 {
+		t.Errorf("Unexpected template %+v", template)
+	}
+
+	if _, err := NewTemplateFile(dir, "review"); err == nil {
+		t.Errorf("Expected error for an existing template file")
+	}
+	if _, err := NewTemplateFile(dir, "../review"); err == nil {
+		t.Errorf("Expected error for an invalid template name")
+	}
+}
+
+func TestResolveTemplates(t *testing.T) {
+	base := ChatTemplate{
+		Name:        "base",
+		Description: "Coding standards",
+		Params:      []TemplateParam{{Name: "Lang", Default: "Go"}},
+		Messages:    []Message{{Role: "system", Content: "Follow the standards."}},
+	}
+	styleGuide := ChatTemplate{
+		Name:     "style-guide",
+		Messages: []Message{{Role: "system", Content: "Follow the style guide."}},
+	}
+	security := ChatTemplate{
+		Name:     "security-rules",
+		Extends:  "style-guide",
+		Messages: []Message{{Role: "system", Content: "Check security."}},
+	}
+	reviewer := ChatTemplate{
+		Name:     "reviewer",
+		Extends:  "base",
+		Include:  []string{"style-guide", "security-rules"},
+		Params:   []TemplateParam{{Name: "Lang", Default: "Rust"}},
+		Messages: []Message{{Role: "user", Content: "Review {{.Lang}}."}},
+	}
+
+	templates, errs := ResolveTemplates([]ChatTemplate{reviewer, base, styleGuide, security})
+	if len(errs) != 0 {
+		t.Fatalf("Error: Return err func: %v", errs)
+	}
+
+	var contents []string
+	for _, message := range templates[0].Messages {
+		contents = append(contents, message.Content)
+	}
+	expected := "Follow the standards.|Follow the style guide.|Check security.|Review {{.Lang}}."
+	if strings.Join(contents, "|") != expected {
+		t.Errorf("Expected %q but got %q", expected, strings.Join(contents, "|"))
+	}
+	if len(templates[0].Params) != 1 || templates[0].Params[0].Default != "Rust" {
+		t.Errorf("Unexpected params %+v", templates[0].Params)
+	}
+	if templates[0].Description != "Coding standards" {
+		t.Errorf("Expected %q but got %q", "Coding standards", templates[0].Description)
+	}
+	if len(templates[1].Messages) != 1 {
+		t.Errorf("Unexpected base messages %+v", templates[1].Messages)
+	}
+
+	testCases := []struct {
+		name          string
+		templates     []ChatTemplate
+		expectedError string
+	}{
+		{
+			"cycle",
+			[]ChatTemplate{{Name: "a", Extends: "b", FilePath: "a.toml"}, {Name: "b", Include: []string{"a"}, FilePath: "b.toml"}},
+			"template b (b.toml): cycle in extends or include: a -> b -> a",
+		},
+		{
+			"self",
+			[]ChatTemplate{{Name: "a", Extends: "a", FilePath: "a.toml"}},
+			"template a (a.toml): cycle in extends or include: a -> a",
+		},
+		{
+			"unknown",
+			[]ChatTemplate{{Name: "a", Extends: "bsae", FilePath: "a.toml"}, {Name: "base"}},
+			"template a (a.toml): unknown template bsae (did you mean base?)",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			templates, errs := ResolveTemplates(tc.templates)
+			if len(errs) == 0 || errs[0].Error() != tc.expectedError {
+				t.Fatalf("Expected %q but got %v", tc.expectedError, errs)
+			}
+
+			template, err := FindTemplate(templates, "a")
+			if template == nil || err == nil || err.Error() != tc.expectedError {
+				t.Errorf("Expected template a with %q but got %+v, %v", tc.expectedError, template, err)
+			}
+		})
+	}
+}
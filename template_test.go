@@ -0,0 +1,225 @@
+package oax
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChatTemplateRender(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(file, []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Error: Cannot write file: %v", err)
+	}
+	t.Setenv("OAX_TEST_USER", "alice")
+
+	template := ChatTemplate{
+		Name: "reviewer",
+		Params: []TemplateParam{
+			{Name: "Lang", Description: "Language", Default: "Go"},
+			{Name: "File"},
+		},
+		Messages: []Message{
+			{Role: "system", Content: `You review {{.Lang}} code for {{env "OAX_TEST_USER"}}.`},
+			{Role: "user", Content: "{{.File | include}}\n{{if .Focus}}Focus on {{.Focus}}.{{end}}"},
+		},
+	}
+
+	var prompted []string
+	messages, err := template.Render(&RenderTemplateOption{
+		Vars: map[string]string{"File": file},
+		Prompt: func(param TemplateParam) (string, error) {
+			prompted = append(prompted, param.Name)
+
+			return "errors", nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+
+	expected := []Message{
+		{Role: "system", Content: "You review Go code for alice."},
+		{Role: "user", Content: "package main\nFocus on errors."},
+	}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Errorf("Expected %q but got %q", expected, messages)
+	}
+	if !reflect.DeepEqual(prompted, []string{"Focus"}) {
+		t.Errorf("Expected %q but got %q", []string{"Focus"}, prompted)
+	}
+
+	if _, err := template.Render(&RenderTemplateOption{}); err == nil {
+		t.Errorf("Expected error for a missing variable without a prompt")
+	}
+}
+
+func TestChatTemplateRenderVerbatim(t *testing.T) {
+	literal := []Message{{Role: "system", Content: "Write Handlebars like {{name}} and Go like {{.Name}}."}}
+
+	testCases := []struct {
+		name     string
+		template ChatTemplate
+		expected []Message
+	}{
+		{
+			"without params",
+			ChatTemplate{Name: "literal", Messages: literal},
+			literal,
+		},
+		{
+			"expand",
+			ChatTemplate{Name: "expand", Expand: true, Messages: []Message{{Role: "system", Content: `{{"{{"}}name}} for {{env "OAX_TEST_USER"}}`}}},
+			[]Message{{Role: "system", Content: "{{name}} for alice"}},
+		},
+	}
+
+	t.Setenv("OAX_TEST_USER", "alice")
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			messages, err := tc.template.Render(&RenderTemplateOption{})
+			if err != nil {
+				t.Fatalf("Error: Return err func: %v", err)
+			}
+			if !reflect.DeepEqual(messages, tc.expected) {
+				t.Errorf("Expected %q but got %q", tc.expected, messages)
+			}
+		})
+	}
+
+	template := ChatTemplate{Name: "literal", Messages: literal}
+
+	var warnings []string
+	if _, err := template.Render(&RenderTemplateOption{Warn: func(message string) { warnings = append(warnings, message) }}); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "template literal is sent as it is") {
+		t.Errorf("Expected a warning about {{ but got %q", warnings)
+	}
+
+	_, err := template.Render(&RenderTemplateOption{Vars: map[string]string{"Name": "x"}})
+	if err == nil || !strings.Contains(err.Error(), "--var has no effect") {
+		t.Errorf("Expected an error for --var but got %v", err)
+	}
+}
+
+func TestApplyTemplate(t *testing.T) {
+	templateMessages := []Message{
+		{Role: "system", Content: "You review code."},
+		{Role: "user", Content: "Review this."},
+	}
+	conversation := []ChatMessage{
+		{Role: "system", Content: "You are helpful."},
+		{Role: "user", Content: "Hi"},
+		{Role: "assistant", Content: "Hello"},
+	}
+
+	testCases := []struct {
+		name             string
+		messages         []Message
+		mode             string
+		expectedRoles    string
+		expectedFirst    string
+		expectedTemplate string
+		expectedErr      bool
+	}{
+		{"new", templateMessages, TemplateModeNew, "system,user,assistant,system,user", "You are helpful.", "review:new", false},
+		{"system", templateMessages, TemplateModeSystem, "system,user,assistant", "You review code.", "review:system", false},
+		{"append", templateMessages, TemplateModeAppend, "system,user,assistant,user", "You are helpful.", "review:append", false},
+		{"system without system messages", templateMessages[1:], TemplateModeSystem, "", "", "", true},
+		{"append without other messages", templateMessages[:1], TemplateModeAppend, "", "", "", true},
+		{"unknown mode", templateMessages, "replace", "", "", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			chatLog := &ChatLog{ChatLogToml: ChatLogToml{Messages: append([]ChatMessage(nil), conversation...)}}
+
+			err := chatLog.ApplyTemplate("review", tc.messages, tc.mode)
+			if tc.expectedErr {
+				if err == nil {
+					t.Fatalf("Expected error but got %+v", chatLog.ChatLogToml)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("Error: Return err func: %v", err)
+			}
+
+			var roles []string
+			for _, message := range chatLog.ChatLogToml.Messages {
+				roles = append(roles, message.Role)
+			}
+			if strings.Join(roles, ",") != tc.expectedRoles {
+				t.Errorf("Expected %q but got %q", tc.expectedRoles, strings.Join(roles, ","))
+			}
+			if chatLog.ChatLogToml.Messages[0].Content != tc.expectedFirst {
+				t.Errorf("Expected %q but got %q", tc.expectedFirst, chatLog.ChatLogToml.Messages[0].Content)
+			}
+			if len(chatLog.ChatLogToml.Templates) != 1 || chatLog.ChatLogToml.Templates[0] != tc.expectedTemplate {
+				t.Errorf("Expected %q but got %q", tc.expectedTemplate, chatLog.ChatLogToml.Templates)
+			}
+		})
+	}
+}
+
+func TestChatTemplateRenderProject(t *testing.T) {
+	root := t.TempDir()
+	inside := filepath.Join(root, "README.md")
+	outside := filepath.Join(t.TempDir(), "secret")
+	for _, file := range []string{inside, outside} {
+		if err := os.WriteFile(file, []byte(filepath.Base(file)+"\n"), 0644); err != nil {
+			t.Fatalf("Error: Cannot write file: %v", err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Skipf("symbolic links are not available: %v", err)
+	}
+	t.Setenv("OAX_TEST_USER", "alice")
+
+	// A global template that includes a project template is restricted too.
+	templates, errs := ResolveTemplates([]ChatTemplate{
+		{Name: "global", Include: []string{"project"}, Expand: true},
+		{Name: "project", projectRoot: root},
+	})
+	if len(errs) != 0 {
+		t.Fatalf("Error: Return err func: %v", errs)
+	}
+	template := templates[0]
+
+	testCases := []struct {
+		name          string
+		content       string
+		expected      string
+		expectedError string
+	}{
+		{"include in the project", `{{include "` + inside + `"}}`, "README.md", ""},
+		{"include outside the project", `{{include "` + outside + `"}}`, "", "outside the project"},
+		{"include through a link", `{{include "` + filepath.Join(root, "link") + `"}}`, "", "outside the project"},
+		{"env", `{{env "OAX_TEST_USER"}}`, "", "env is not available"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			template.Messages = []Message{{Role: "system", Content: tc.content}}
+
+			messages, err := template.Render(&RenderTemplateOption{})
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Errorf("Expected an error with %q but got %v", tc.expectedError, err)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("Error: Return err func: %v", err)
+			}
+			if messages[0].Content != tc.expected {
+				t.Errorf("Expected %q but got %q", tc.expected, messages[0].Content)
+			}
+		})
+	}
+}
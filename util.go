@@ -1,28 +1,40 @@
 package oax
 
 import (
+	"fmt"
 	"io/fs"
 	"os"
 	"os/user"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 )
 
 func createDirIfNotExist(dirPath string) error {
 	if _, err := os.Stat(dirPath); os.IsNotExist(err) {
-		return os.Mkdir(dirPath, 0755)
+		return os.MkdirAll(dirPath, 0755)
 	}
 	return nil
 }
 
+// homeDir returns the home directory of the current user, falling back to
+// $HOME when there is no passwd entry, as in some containers.
+func homeDir() (string, error) {
+	usr, err := user.Current()
+	if err == nil && usr.HomeDir != "" {
+		return usr.HomeDir, nil
+	}
+
+	return os.UserHomeDir()
+}
+
 func replaceTildeWithHomedir(path string) (string, error) {
 	if strings.HasPrefix(path, "~") {
-		usr, err := user.Current()
+		homeDir, err := homeDir()
 		if err != nil {
 			return "", err
 		}
-		homeDir := usr.HomeDir
 		path = strings.Replace(path, "~", homeDir, 1)
 	}
 
@@ -30,11 +42,10 @@ func replaceTildeWithHomedir(path string) (string, error) {
 }
 
 func replaceHomedirWithTilde(path string) (string, error) {
-	usr, err := user.Current()
+	homedir, err := homeDir()
 	if err != nil {
 		return "", err
 	}
-	homedir := usr.HomeDir
 
 	if strings.HasPrefix(path, homedir) {
 		return strings.Replace(path, homedir, "~", 1), nil
@@ -43,25 +54,129 @@ func replaceHomedirWithTilde(path string) (string, error) {
 	return path, nil
 }
 
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+
+	return err == nil
+}
+
+// sanitizeFileName replaces characters that cannot appear in a file name
+// on the supported platforms.
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r < 0x20, r == 0x7f:
+			return -1
+		case strings.ContainsRune(`/\:*?"<>|`, r):
+			return '_'
+		default:
+			return r
+		}
+	}, strings.TrimSpace(name))
+}
+
 type FileInfo struct {
 	FileFullPath string
 	FileName     string
+	// Title is the file name without its extension.
+	Title string
+	// Group is the subdirectory of the listed directory the file is in, or
+	// empty for files directly in it.
+	Group      string
+	ModTime    time.Time
+	CreateTime time.Time
 }
 
-func ListFiles(dir string) (fileInfos []FileInfo, err error) {
+const (
+	SortByModified = "modified"
+	SortByCreated  = "created"
+	SortByTitle    = "title"
+)
+
+type ListOption struct {
+	// Sort is one of SortByModified (default), SortByCreated or
+	// SortByTitle. Times sort newest first, titles alphabetically.
+	Sort string
+	// Recursive includes chat logs in subdirectories, listed as groups
+	// after the chat logs directly in the directory.
+	Recursive bool
+	// IncludeArchived includes the archive subdirectory even when Recursive
+	// is not set.
+	IncludeArchived bool
+}
+
+// ListFiles lists the chat logs in dir. Hidden files and directories,
+// editor swap files, backups and any file without a chat log extension
+// are skipped.
+func ListFiles(dir string, opt ListOption) (fileInfos []FileInfo, err error) {
+	switch opt.Sort {
+	case "", SortByModified, SortByCreated, SortByTitle:
+	default:
+		return nil, fmt.Errorf("unknown chat log sort %q (supported: %s, %s, %s)", opt.Sort, SortByModified, SortByCreated, SortByTitle)
+	}
+
 	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 
-		if !d.IsDir() {
-			fileInfo := FileInfo{
-				FileName:     filepath.Base(path),
-				FileFullPath: path,
+		if path == dir {
+			return nil
+		}
+
+		if strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
 			}
-			fileInfos = append(fileInfos, fileInfo)
+
+			return nil
 		}
 
+		group := topLevelDir(dir, path)
+
+		if d.IsDir() {
+			if group == "" && d.Name() == ArchiveDirName {
+				if !opt.IncludeArchived {
+					return filepath.SkipDir
+				}
+
+				return nil
+			}
+			if !opt.Recursive && !(group == ArchiveDirName && opt.IncludeArchived) {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !d.Type().IsRegular() || !IsChatLogFile(path) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			rel = ""
+		}
+
+		name := filepath.Base(path)
+		fileInfo := FileInfo{
+			FileName:     name,
+			FileFullPath: path,
+			Title:        strings.TrimSuffix(name, filepath.Ext(name)),
+			Group:        filepath.ToSlash(rel),
+			ModTime:      info.ModTime(),
+			CreateTime:   fileCreateTime(path, info),
+		}
+		fileInfos = append(fileInfos, fileInfo)
+
 		return nil
 	})
 
@@ -69,8 +184,29 @@ func ListFiles(dir string) (fileInfos []FileInfo, err error) {
 		return
 	}
 
-	sort.Slice(fileInfos, func(i, j int) bool {
-		return fileInfos[i].FileName > fileInfos[j].FileName
+	sort.SliceStable(fileInfos, func(i, j int) bool {
+		a, b := fileInfos[i], fileInfos[j]
+
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+
+		switch opt.Sort {
+		case SortByTitle:
+			if a.Title != b.Title {
+				return a.Title < b.Title
+			}
+		case SortByCreated:
+			if !a.CreateTime.Equal(b.CreateTime) {
+				return a.CreateTime.After(b.CreateTime)
+			}
+		default:
+			if !a.ModTime.Equal(b.ModTime) {
+				return a.ModTime.After(b.ModTime)
+			}
+		}
+
+		return a.FileName > b.FileName
 	})
 
 	return
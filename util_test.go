@@ -1,9 +1,13 @@
 package oax
 
 import (
+	"os"
 	"os/user"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestReplaceTildeWithHomedir(t *testing.T) {
@@ -69,3 +73,72 @@ func TestReplaceHomedirWithTilde(t *testing.T) {
 		})
 	}
 }
+
+func TestListFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]time.Time{
+		"b-title.toml":              time.Date(2023, 4, 3, 0, 0, 0, 0, time.UTC),
+		"a-title.md":                time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC),
+		"c-title.jsonl":             time.Date(2023, 4, 2, 0, 0, 0, 0, time.UTC),
+		"a-title.md.bak":            time.Date(2023, 4, 4, 0, 0, 0, 0, time.UTC),
+		".a-title.md.swp":           time.Date(2023, 4, 4, 0, 0, 0, 0, time.UTC),
+		"b-title.toml~":             time.Date(2023, 4, 4, 0, 0, 0, 0, time.UTC),
+		"notes.txt":                 time.Date(2023, 4, 4, 0, 0, 0, 0, time.UTC),
+		"project/d-title.toml":      time.Date(2023, 4, 5, 0, 0, 0, 0, time.UTC),
+		"archive/e-title.toml":      time.Date(2023, 4, 6, 0, 0, 0, 0, time.UTC),
+		".trash/1_f-title.toml":     time.Date(2023, 4, 7, 0, 0, 0, 0, time.UTC),
+		"project/.hidden/g.toml":    time.Date(2023, 4, 8, 0, 0, 0, 0, time.UTC),
+		"project/sub/h-title.toml":  time.Date(2023, 4, 9, 0, 0, 0, 0, time.UTC),
+		"project/sub/i-title.md.sw": time.Date(2023, 4, 9, 0, 0, 0, 0, time.UTC),
+	}
+	for name, modTime := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Error: Cannot create dir: %v", err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatalf("Error: Cannot write file: %v", err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("Error: Cannot change times: %v", err)
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		opt      ListOption
+		expected []string
+	}{
+		{"modified", ListOption{}, []string{"b-title", "c-title", "a-title"}},
+		{"title", ListOption{Sort: SortByTitle}, []string{"a-title", "b-title", "c-title"}},
+		{"recursive", ListOption{Recursive: true}, []string{"b-title", "c-title", "a-title", "project/d-title", "project/sub/h-title"}},
+		{"archived", ListOption{IncludeArchived: true}, []string{"b-title", "c-title", "a-title", "archive/e-title"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fileInfos, err := ListFiles(dir, tc.opt)
+			if err != nil {
+				t.Fatalf("Error: Return err func: %v", err)
+			}
+
+			var result []string
+			for _, fileInfo := range fileInfos {
+				name := fileInfo.Title
+				if fileInfo.Group != "" {
+					name = fileInfo.Group + "/" + name
+				}
+				result = append(result, name)
+			}
+
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("Expected %q but got %q", tc.expected, result)
+			}
+		})
+	}
+
+	if _, err := ListFiles(dir, ListOption{Sort: "size"}); err == nil {
+		t.Errorf("Expected an error for an unknown sort")
+	}
+}
@@ -0,0 +1,546 @@
+package oax
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+)
+
+// Diagnostic is a problem found in a configuration file.
+type Diagnostic struct {
+	FilePath string
+	// Line and Col are zero when the problem has no position.
+	Line    int
+	Col     int
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	if d.Line == 0 {
+		return fmt.Sprintf("%s: %s", d.FilePath, d.Message)
+	}
+
+	return fmt.Sprintf("%s:%d:%d: %s", d.FilePath, d.Line, d.Col, d.Message)
+}
+
+var validRoles = []string{"system", "user", "assistant"}
+
+// ValidateConfig checks the settings, profiles and project settings files
+// for unknown keys, values of the wrong type, duplicate or multiple default
+// profiles, template messages with invalid roles and an editor that cannot
+// be found. It only reads the files, so it works when GetConfig fails.
+func ValidateConfig(opt *ConfigOption) ([]Diagnostic, error) {
+	return validateConfig(opt, true)
+}
+
+// validateConfig runs the checks of ValidateConfig, looking up the editor
+// only with checkEditor.
+func validateConfig(opt *ConfigOption, checkEditor bool) ([]Diagnostic, error) {
+	var flagConfigDir string
+	if opt != nil {
+		flagConfigDir = opt.ConfigDir
+	}
+
+	configDir, _, err := getConfigDir(flagConfigDir)
+	if err != nil {
+		return nil, err
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	project, err := FindProject(wd)
+	if err != nil {
+		return nil, err
+	}
+
+	var diagnostics []Diagnostic
+
+	globalSettingFilePath := filepath.Join(configDir, "settings.toml")
+	// Each settings file is followed by its template directory, the order
+	// in which GetConfig merges templates.
+	type settingFile struct {
+		filePath    string
+		templateDir string
+	}
+	settingFiles := []settingFile{{globalSettingFilePath, filepath.Join(configDir, TemplatesDirName)}}
+	if project != nil {
+		settingFiles = append(settingFiles, settingFile{project.SettingFilePath, filepath.Join(project.Root, ProjectDirName, TemplatesDirName)})
+	}
+
+	editor := DefaultEditor
+	editorPosition := Diagnostic{FilePath: globalSettingFilePath}
+	var templates []ChatTemplate
+	for _, settingFile := range settingFiles {
+		filePath := settingFile.filePath
+
+		if tree, diagnostic := loadTreeForValidation(filePath); tree == nil {
+			diagnostics = append(diagnostics, diagnostic)
+		} else {
+			diagnostics = append(diagnostics, validateTree(filePath, tree, reflect.TypeOf(Settings{}), "")...)
+			diagnostics = append(diagnostics, validateSettingValues(filePath, tree)...)
+
+			// The editor of a project is ignored by GetConfig.
+			if value, ok := tree.Get("setting.editor").(string); ok && value != "" && filePath == globalSettingFilePath {
+				editor = value
+				editorPosition = newDiagnostic(filePath, tree.GetPosition("setting.editor"), "")
+			}
+
+			var settings Settings
+			if tree.Unmarshal(&settings) == nil {
+				setTemplateFilePath(settings.Chat.Templates, filePath)
+				mergeTemplates(&templates, settings.Chat.Templates)
+			}
+		}
+
+		diagnostics = append(diagnostics, validateTemplateDir(settingFile.templateDir)...)
+		dirTemplates, _ := LoadTemplateDir(settingFile.templateDir)
+		mergeTemplates(&templates, dirTemplates)
+	}
+
+	// A template that extends a broken one has the error of that one, which
+	// is reported once.
+	_, errs := ResolveTemplates(templates)
+	reported := map[string]bool{}
+	for _, err := range errs {
+		var templateErr *TemplateError
+		if errors.As(err, &templateErr) && !reported[err.Error()] {
+			reported[err.Error()] = true
+			diagnostics = append(diagnostics, Diagnostic{
+				FilePath: templateErr.FilePath,
+				Message:  fmt.Sprintf("template %s: %v", templateErr.Name, templateErr.Err),
+			})
+		}
+	}
+
+	profilesFilePath := filepath.Join(configDir, "profiles.toml")
+	if tree, diagnostic := loadTreeForValidation(profilesFilePath); tree == nil {
+		diagnostics = append(diagnostics, diagnostic)
+	} else {
+		diagnostics = append(diagnostics, validateTree(profilesFilePath, tree, reflect.TypeOf(ProfileToml{}), "")...)
+		diagnostics = append(diagnostics, validateProfiles(profilesFilePath, tree)...)
+	}
+
+	if diagnostic, ok := validateProfilesMode(profilesFilePath); !ok {
+		diagnostics = append(diagnostics, diagnostic)
+	}
+
+	if checkEditor {
+		if _, err := exec.LookPath(editor); err != nil {
+			editorPosition.Message = fmt.Sprintf("editor %q not found in PATH", editor)
+			diagnostics = append(diagnostics, editorPosition)
+		}
+	}
+
+	fileOrder := map[string]int{}
+	for _, diagnostic := range diagnostics {
+		if _, ok := fileOrder[diagnostic.FilePath]; !ok {
+			fileOrder[diagnostic.FilePath] = len(fileOrder)
+		}
+	}
+
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		if a.FilePath != b.FilePath {
+			return fileOrder[a.FilePath] < fileOrder[b.FilePath]
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+
+		return a.Col < b.Col
+	})
+
+	return diagnostics, nil
+}
+
+// loadTreeForValidation returns nil and the parse error as a diagnostic
+// when the file cannot be read. A missing file is an empty tree.
+func loadTreeForValidation(filePath string) (*toml.Tree, Diagnostic) {
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		tree, _ := toml.Load("")
+
+		return tree, Diagnostic{}
+	}
+	if err != nil {
+		return nil, Diagnostic{FilePath: filePath, Message: err.Error()}
+	}
+
+	tree, err := toml.LoadBytes(data)
+	if err != nil {
+		return nil, Diagnostic{FilePath: filePath, Message: err.Error()}
+	}
+
+	return tree, Diagnostic{}
+}
+
+// validateTree reports keys of tree that have no field in the struct type t
+// and values whose type does not match their field.
+func validateTree(filePath string, tree *toml.Tree, t reflect.Type, prefix string) []Diagnostic {
+	var diagnostics []Diagnostic
+
+	fields := map[string]reflect.StructField{}
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		name := tomlFieldName(t.Field(i))
+		if name == "-" {
+			continue
+		}
+		fields[name] = t.Field(i)
+		names = append(names, name)
+	}
+
+	for _, key := range tree.Keys() {
+		position := tree.GetPosition(key)
+
+		field, ok := fields[key]
+		if !ok {
+			message := fmt.Sprintf("unknown key %s%s", prefix, key)
+			if suggestion := suggest(key, names); suggestion != "" {
+				message += fmt.Sprintf(" (did you mean %s%s?)", prefix, suggestion)
+			}
+			diagnostics = append(diagnostics, newDiagnostic(filePath, position, message))
+
+			continue
+		}
+
+		diagnostics = append(diagnostics, validateValue(filePath, tree.Get(key), field.Type, prefix+key, position)...)
+	}
+
+	return diagnostics
+}
+
+func validateValue(filePath string, value interface{}, t reflect.Type, key string, position toml.Position) []Diagnostic {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	typeError := func() []Diagnostic {
+		return []Diagnostic{newDiagnostic(filePath, position, fmt.Sprintf("%s must be %s but got %s", key, tomlTypeName(t), tomlValueTypeName(value)))}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		tree, ok := value.(*toml.Tree)
+		if !ok {
+			return typeError()
+		}
+
+		return validateTree(filePath, tree, t, key+".")
+	case reflect.Map:
+		tree, ok := value.(*toml.Tree)
+		if !ok {
+			return typeError()
+		}
+
+		var diagnostics []Diagnostic
+		for _, name := range tree.Keys() {
+			diagnostics = append(diagnostics, validateValue(filePath, tree.Get(name), t.Elem(), key+"."+name, tree.GetPosition(name))...)
+		}
+
+		return diagnostics
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Struct {
+			trees, ok := value.([]*toml.Tree)
+			if !ok {
+				return typeError()
+			}
+
+			var diagnostics []Diagnostic
+			for i, tree := range trees {
+				diagnostics = append(diagnostics, validateTree(filePath, tree, t.Elem(), fmt.Sprintf("%s[%d].", key, i))...)
+			}
+
+			return diagnostics
+		}
+
+		items, ok := value.([]interface{})
+		if !ok {
+			return typeError()
+		}
+
+		var diagnostics []Diagnostic
+		for i, item := range items {
+			diagnostics = append(diagnostics, validateValue(filePath, item, t.Elem(), fmt.Sprintf("%s[%d]", key, i), position)...)
+		}
+
+		return diagnostics
+	case reflect.String:
+		if _, ok := value.(string); !ok {
+			return typeError()
+		}
+	case reflect.Bool:
+		if _, ok := value.(bool); !ok {
+			return typeError()
+		}
+	case reflect.Int, reflect.Int64:
+		if _, ok := value.(int64); !ok {
+			return typeError()
+		}
+	case reflect.Float64:
+		switch value.(type) {
+		case float64, int64:
+		default:
+			return typeError()
+		}
+	}
+
+	return nil
+}
+
+// validateSettingValues checks values of a settings file that must be one
+// of a fixed set.
+func validateSettingValues(filePath string, tree *toml.Tree) []Diagnostic {
+	var diagnostics []Diagnostic
+
+	if format, ok := tree.Get("setting.chatLogFormat").(string); ok && format != "" {
+		if _, err := ChatLogFormatByName(format); err != nil {
+			diagnostics = append(diagnostics, newDiagnostic(filePath, tree.GetPosition("setting.chatLogFormat"), err.Error()))
+		}
+	}
+
+	if chatLogSort, ok := tree.Get("setting.chatLogSort").(string); ok {
+		switch chatLogSort {
+		case "", SortByModified, SortByCreated, SortByTitle:
+		default:
+			diagnostics = append(diagnostics, newDiagnostic(filePath, tree.GetPosition("setting.chatLogSort"),
+				fmt.Sprintf("unknown chat log sort %q (supported: %s, %s, %s)", chatLogSort, SortByModified, SortByCreated, SortByTitle)))
+		}
+	}
+
+	if render, ok := tree.Get("output.render").(string); ok {
+		switch render {
+		case "", RenderMarkdown, RenderRaw:
+		default:
+			diagnostics = append(diagnostics, newDiagnostic(filePath, tree.GetPosition("output.render"),
+				fmt.Sprintf("unknown output render %q (supported: %s, %s)", render, RenderMarkdown, RenderRaw)))
+		}
+	}
+
+	if theme, ok := tree.Get("output.theme").(string); ok && theme != "" && !IsTheme(theme) {
+		diagnostics = append(diagnostics, newDiagnostic(filePath, tree.GetPosition("output.theme"),
+			fmt.Sprintf("unknown output theme %q", theme)))
+	}
+
+	if models, ok := tree.Get("models").(*toml.Tree); ok {
+		for _, name := range models.Keys() {
+			model, ok := models.Get(name).(*toml.Tree)
+			if !ok {
+				continue
+			}
+			if provider, ok := model.Get("provider").(string); ok && provider != "" && provider != ProviderOpenAI {
+				diagnostics = append(diagnostics, newDiagnostic(filePath, model.GetPosition("provider"),
+					fmt.Sprintf("models.%s.provider %q is not supported (supported: %s)", name, provider, ProviderOpenAI)))
+			}
+			if !model.Has("model") {
+				diagnostics = append(diagnostics, newDiagnostic(filePath, model.Position(), fmt.Sprintf("models.%s has no model", name)))
+			}
+		}
+	}
+
+	templates, _ := tree.Get("chat.templates").([]*toml.Tree)
+	for i, template := range templates {
+		diagnostics = append(diagnostics, validateTemplateMessages(filePath, template, fmt.Sprintf("chat.templates[%d].", i))...)
+	}
+
+	return diagnostics
+}
+
+// validateProfilesMode checks that profiles.toml, which may contain API
+// keys, is not readable by others. It is cheap enough to run on startup.
+func validateProfilesMode(profilesFilePath string) (Diagnostic, bool) {
+	info, err := os.Stat(profilesFilePath)
+	if err != nil || runtime.GOOS == "windows" || info.Mode().Perm()&0044 == 0 {
+		return Diagnostic{}, true
+	}
+
+	return Diagnostic{
+		FilePath: profilesFilePath,
+		Message:  fmt.Sprintf("readable by group or others (mode %04o); run chmod 600 %s", info.Mode().Perm(), profilesFilePath),
+	}, false
+}
+
+func validateTemplateMessages(filePath string, template *toml.Tree, prefix string) []Diagnostic {
+	var diagnostics []Diagnostic
+
+	messages, _ := template.Get("messages").([]*toml.Tree)
+	for i, message := range messages {
+		role, ok := message.Get("role").(string)
+		if ok && !containsString(validRoles, role) {
+			diagnostics = append(diagnostics, newDiagnostic(filePath, message.GetPosition("role"),
+				fmt.Sprintf("%smessages[%d].role %q must be one of %s", prefix, i, role, strings.Join(validRoles, ", "))))
+		}
+	}
+
+	return diagnostics
+}
+
+// validateTemplateDir checks the template files in dir.
+func validateTemplateDir(dir string) []Diagnostic {
+	var diagnostics []Diagnostic
+
+	filePaths, _ := filepath.Glob(filepath.Join(dir, "*"+templateFileExtension))
+	sort.Strings(filePaths)
+	for _, filePath := range filePaths {
+		tree, diagnostic := loadTreeForValidation(filePath)
+		if tree == nil {
+			diagnostics = append(diagnostics, diagnostic)
+
+			continue
+		}
+
+		diagnostics = append(diagnostics, validateTree(filePath, tree, reflect.TypeOf(ChatTemplate{}), "")...)
+		diagnostics = append(diagnostics, validateTemplateMessages(filePath, tree, "")...)
+	}
+
+	return diagnostics
+}
+
+func validateProfiles(filePath string, tree *toml.Tree) []Diagnostic {
+	var diagnostics []Diagnostic
+
+	profiles, _ := tree.Get("profiles").([]*toml.Tree)
+	names := map[string]bool{}
+	var defaults []string
+
+	for i, profile := range profiles {
+		name, _ := profile.Get("name").(string)
+		switch {
+		case name == "":
+			diagnostics = append(diagnostics, newDiagnostic(filePath, profile.Position(), fmt.Sprintf("profiles[%d] has no name", i)))
+		case names[name]:
+			diagnostics = append(diagnostics, newDiagnostic(filePath, profile.GetPosition("name"), fmt.Sprintf("duplicate profile name %q", name)))
+		}
+		names[name] = true
+
+		if isDefault, _ := profile.Get("default").(bool); isDefault {
+			defaults = append(defaults, name)
+			if len(defaults) > 1 {
+				diagnostics = append(diagnostics, newDiagnostic(filePath, profile.GetPosition("default"),
+					fmt.Sprintf("multiple default profiles: %s", strings.Join(defaults, ", "))))
+			}
+		}
+	}
+
+	return diagnostics
+}
+
+func newDiagnostic(filePath string, position toml.Position, message string) Diagnostic {
+	return Diagnostic{
+		FilePath: filePath,
+		Line:     position.Line,
+		Col:      position.Col,
+		Message:  message,
+	}
+}
+
+func tomlTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map:
+		return "a table"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Struct {
+			return "an array of tables"
+		}
+
+		return "an array"
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int64:
+		return "an integer"
+	case reflect.Float64:
+		return "a number"
+	default:
+		return t.String()
+	}
+}
+
+func tomlValueTypeName(value interface{}) string {
+	switch value.(type) {
+	case *toml.Tree:
+		return "a table"
+	case []*toml.Tree:
+		return "an array of tables"
+	case []interface{}:
+		return "an array"
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case int64:
+		return "an integer"
+	case float64:
+		return "a float"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// suggest returns the candidate closest to name, ignoring case, or an empty
+// string when none is close enough to be a typo.
+func suggest(name string, candidates []string) string {
+	best := ""
+	bestDistance := len(name)/3 + 1
+
+	for _, candidate := range candidates {
+		distance := editDistance(strings.ToLower(name), strings.ToLower(candidate))
+		if distance < bestDistance {
+			best = candidate
+			bestDistance = distance
+		}
+	}
+
+	return best
+}
+
+// editDistance is the Damerau-Levenshtein distance, counting swapped
+// adjacent characters as one edit.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			d[i][j] = minInt(d[i-1][j]+1, minInt(d[i][j-1]+1, d[i-1][j-1]+cost))
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = minInt(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(ra)][len(rb)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
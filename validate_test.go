@@ -0,0 +1,107 @@
+package oax
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestValidateConfig(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not checked on Windows")
+	}
+
+	configDir := t.TempDir()
+	settingPath := filepath.Join(configDir, "settings.toml")
+	profilePath := filepath.Join(configDir, "profiles.toml")
+
+	if err := os.WriteFile(settingPath, []byte(`[setting]
+  editor = "oax-missing-editor"
+  chatlogdir = "~/chat"
+  chatLogRecursive = "yes"
+
+[chat]
+  [[chat.templates]]
+    name = "reviewer"
+
+    [[chat.templates.messages]]
+      role = "sytem"
+      content = "You review code."
+
+[output]
+  render = "html"
+  theme = "oax-missing-theme"
+`), 0644); err != nil {
+		t.Fatalf("Error: Cannot write file: %v", err)
+	}
+	if err := os.WriteFile(profilePath, []byte(`[[profiles]]
+  name = "me"
+  apiKey = "sk-xxxx"
+  defualt = true
+
+[[profiles]]
+  name = "me"
+  default = true
+
+[[profiles]]
+  name = "work"
+  default = true
+`), 0644); err != nil {
+		t.Fatalf("Error: Cannot write file: %v", err)
+	}
+
+	if err := os.Chmod(profilePath, 0644); err != nil {
+		t.Fatalf("Error: Cannot chmod file: %v", err)
+	}
+
+	diagnostics, err := ValidateConfig(&ConfigOption{ConfigDir: configDir})
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+
+	var got []string
+	for _, diagnostic := range diagnostics {
+		got = append(got, diagnostic.String())
+	}
+
+	expected := []string{
+		settingPath + `:2:3: editor "oax-missing-editor" not found in PATH`,
+		settingPath + `:3:3: unknown key setting.chatlogdir (did you mean setting.chatLogDir?)`,
+		settingPath + `:4:3: setting.chatLogRecursive must be a boolean but got a string`,
+		settingPath + `:11:7: chat.templates[0].messages[0].role "sytem" must be one of system, user, assistant`,
+		settingPath + `:15:3: unknown output render "html" (supported: markdown, raw)`,
+		settingPath + `:16:3: unknown output theme "oax-missing-theme"`,
+		profilePath + `: readable by group or others (mode 0644); run chmod 600 ` + profilePath,
+		profilePath + `:4:3: unknown key profiles[0].defualt (did you mean profiles[0].default?)`,
+		profilePath + `:7:3: duplicate profile name "me"`,
+		profilePath + `:12:3: multiple default profiles: me, work`,
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q but got %q", expected, got)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	candidates := []string{"editor", "chatLogDir", "chatLogFormat"}
+
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"chatlogdir", "chatLogDir"},
+		{"edtior", "editor"},
+		{"chatLogFromat", "chatLogFormat"},
+		{"model", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			if result := suggest(tc.input, candidates); result != tc.expected {
+				t.Errorf("Expected %q but got %q", tc.expected, result)
+			}
+		})
+	}
+}
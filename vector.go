@@ -0,0 +1,381 @@
+package oax
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	VectorIndexDirName = "vector-index"
+	embeddingBatchSize = 100
+	// embeddingMaxInputLength keeps inputs below the token limit of the
+	// embedding models (roughly four characters per token).
+	embeddingMaxInputLength = 24000
+	vectorSnippetLength     = 120
+)
+
+// Embedder computes one embedding vector per input.
+type Embedder interface {
+	Embed(ctx context.Context, inputs []string) ([][]float32, error)
+}
+
+// VectorIndex stores message embeddings for semantic search, one file per
+// chat log so that saving a chat only rewrites the vectors of that log.
+// Vectors are reused by message content hash, so only new or edited
+// messages are sent to the embeddings endpoint.
+type VectorIndex struct {
+	dir      string
+	embedder Embedder
+	model    string
+	// Skip excludes messages from the index, such as the placeholder of an
+	// unanswered prompt.
+	Skip func(message ChatMessage) bool
+}
+
+type vectorEntry struct {
+	FilePath string
+	ModTime  time.Time
+	Size     int64
+	Model    string
+	Messages []vectorMessage
+}
+
+type vectorMessage struct {
+	Index   int
+	Role    string
+	Hash    string
+	Snippet string
+	Vector  []float32
+}
+
+type SemanticQuery struct {
+	Text  string
+	Roles []string
+	Since time.Time
+	Until time.Time
+	Limit int
+}
+
+type SemanticResult struct {
+	FilePath string
+	ModTime  time.Time
+	Score    float64
+	Match    SearchMatch
+}
+
+func NewVectorIndex(dir string, embedder Embedder, model string) *VectorIndex {
+	return &VectorIndex{
+		dir:      dir,
+		embedder: embedder,
+		model:    model,
+	}
+}
+
+// UpdateChatLog embeds the messages of a saved chat log that are not in the
+// index yet.
+func (v *VectorIndex) UpdateChatLog(ctx context.Context, chatLog *ChatLog) error {
+	info, err := os.Stat(*chatLog.FilePath)
+	if err != nil {
+		return err
+	}
+
+	previous, err := v.load(*chatLog.FilePath)
+	if err != nil {
+		return err
+	}
+
+	return v.update(ctx, chatLog, info, previous)
+}
+
+// Update brings the index in line with the chat logs in files, embedding
+// changed logs and removing the vectors of deleted ones.
+func (v *VectorIndex) Update(ctx context.Context, files []FileInfo) error {
+	entries, err := v.loadAll()
+	if err != nil {
+		return err
+	}
+
+	seen := map[string]bool{}
+	for _, file := range files {
+		if !IsChatLogFile(file.FileFullPath) {
+			continue
+		}
+		seen[file.FileFullPath] = true
+
+		info, err := os.Stat(file.FileFullPath)
+		if err != nil {
+			return err
+		}
+
+		previous := entries[file.FileFullPath]
+		if previous != nil && previous.Model == v.model && previous.ModTime.Equal(info.ModTime()) && previous.Size == info.Size() {
+			continue
+		}
+
+		chatLog, err := LoadChatLog(file.FileFullPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "skip index: %s\n", err)
+
+			continue
+		}
+
+		if err := v.update(ctx, chatLog, info, previous); err != nil {
+			return err
+		}
+	}
+
+	for path := range entries {
+		if !seen[path] {
+			if err := os.Remove(v.entryPath(path)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (v *VectorIndex) update(ctx context.Context, chatLog *ChatLog, info os.FileInfo, previous *vectorEntry) error {
+	cached := map[string][]float32{}
+	if previous != nil && previous.Model == v.model {
+		for _, message := range previous.Messages {
+			cached[message.Hash] = message.Vector
+		}
+	}
+
+	entry := vectorEntry{
+		FilePath: *chatLog.FilePath,
+		ModTime:  info.ModTime(),
+		Size:     info.Size(),
+		Model:    v.model,
+	}
+
+	var missing []int
+	var inputs []string
+	for i, message := range chatLog.ChatLogToml.Messages {
+		if strings.TrimSpace(message.Content) == "" || (v.Skip != nil && v.Skip(message)) {
+			continue
+		}
+
+		hash := messageHash(message)
+		entry.Messages = append(entry.Messages, vectorMessage{
+			Index:   i,
+			Role:    message.Role,
+			Hash:    hash,
+			Snippet: summarizeText(message.Content, vectorSnippetLength),
+			Vector:  cached[hash],
+		})
+
+		if cached[hash] == nil {
+			missing = append(missing, len(entry.Messages)-1)
+			inputs = append(inputs, truncateString(message.Content, embeddingMaxInputLength))
+		}
+	}
+
+	for start := 0; start < len(inputs); start += embeddingBatchSize {
+		end := start + embeddingBatchSize
+		if end > len(inputs) {
+			end = len(inputs)
+		}
+
+		vectors, err := v.embedder.Embed(ctx, inputs[start:end])
+		if err != nil {
+			return err
+		}
+		if len(vectors) != end-start {
+			return fmt.Errorf("embeddings: expected %d vectors but got %d", end-start, len(vectors))
+		}
+
+		for i, vector := range vectors {
+			entry.Messages[missing[start+i]].Vector = vector
+		}
+	}
+
+	return v.save(entry)
+}
+
+// Search ranks conversations by the highest cosine similarity between the
+// query and any of their messages.
+func (v *VectorIndex) Search(ctx context.Context, query SemanticQuery) ([]SemanticResult, error) {
+	vectors, err := v.embedder.Embed(ctx, []string{query.Text})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("embeddings: expected 1 vector but got %d", len(vectors))
+	}
+	queryVector := vectors[0]
+
+	entries, err := v.loadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []SemanticResult
+	for _, entry := range entries {
+		if entry.Model != v.model {
+			continue
+		}
+		if !query.Since.IsZero() && entry.ModTime.Before(query.Since) {
+			continue
+		}
+		if !query.Until.IsZero() && !entry.ModTime.Before(query.Until) {
+			continue
+		}
+
+		result := SemanticResult{FilePath: entry.FilePath, ModTime: entry.ModTime, Score: -1}
+		for _, message := range entry.Messages {
+			if len(query.Roles) > 0 && !containsString(query.Roles, message.Role) {
+				continue
+			}
+
+			if score := cosineSimilarity(queryVector, message.Vector); score > result.Score {
+				result.Score = score
+				result.Match = SearchMatch{
+					Role:         message.Role,
+					MessageIndex: message.Index,
+					Snippet:      message.Snippet,
+				}
+			}
+		}
+
+		if result.Score > -1 {
+			results = append(results, result)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	if query.Limit > 0 && len(results) > query.Limit {
+		results = results[:query.Limit]
+	}
+
+	return results, nil
+}
+
+func (v *VectorIndex) entryPath(filePath string) string {
+	sum := sha256.Sum256([]byte(filePath))
+
+	return filepath.Join(v.dir, hex.EncodeToString(sum[:16])+".gob")
+}
+
+func (v *VectorIndex) load(filePath string) (*vectorEntry, error) {
+	f, err := os.Open(v.entryPath(filePath))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entry vectorEntry
+	if err := gob.NewDecoder(f).Decode(&entry); err != nil {
+		// A broken entry is rebuilt.
+		return nil, nil
+	}
+
+	return &entry, nil
+}
+
+func (v *VectorIndex) loadAll() (map[string]*vectorEntry, error) {
+	entries := map[string]*vectorEntry{}
+
+	dirEntries, err := os.ReadDir(v.dir)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || filepath.Ext(dirEntry.Name()) != ".gob" {
+			continue
+		}
+
+		f, err := os.Open(filepath.Join(v.dir, dirEntry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		var entry vectorEntry
+		err = gob.NewDecoder(f).Decode(&entry)
+		f.Close()
+		if err != nil {
+			continue
+		}
+
+		entries[entry.FilePath] = &entry
+	}
+
+	return entries, nil
+}
+
+func (v *VectorIndex) save(entry vectorEntry) error {
+	if err := os.MkdirAll(v.dir, 0755); err != nil {
+		return err
+	}
+
+	var buf strings.Builder
+	if err := gob.NewEncoder(&buf).Encode(entry); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(v.entryPath(entry.FilePath), []byte(buf.String()), 0644, false)
+}
+
+func messageHash(message ChatMessage) string {
+	sum := sha256.Sum256([]byte(message.Role + "\x00" + message.Content))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func cosineSimilarity(a []float32, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return -1
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return -1
+	}
+
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// summarizeText joins the words of s on one line and cuts it at maxRunes.
+func summarizeText(s string, maxRunes int) string {
+	s = strings.Join(strings.Fields(s), " ")
+
+	runes := []rune(s)
+	if len(runes) <= maxRunes {
+		return s
+	}
+
+	return string(runes[:maxRunes]) + "..."
+}
+
+func truncateString(s string, maxLength int) string {
+	if len(s) <= maxLength {
+		return s
+	}
+
+	return strings.ToValidUTF8(s[:maxLength], "")
+}
@@ -0,0 +1,108 @@
+package oax
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// keywordEmbedder maps each input to a vector counting a few keywords and
+// records how many inputs it embedded.
+type keywordEmbedder struct {
+	keywords []string
+	embedded int
+}
+
+func (e *keywordEmbedder) Embed(ctx context.Context, inputs []string) ([][]float32, error) {
+	var vectors [][]float32
+	for _, input := range inputs {
+		vector := make([]float32, len(e.keywords))
+		for i, keyword := range e.keywords {
+			vector[i] = float32(strings.Count(strings.ToLower(input), keyword))
+		}
+		vectors = append(vectors, vector)
+	}
+	e.embedded += len(inputs)
+
+	return vectors, nil
+}
+
+func TestVectorIndex(t *testing.T) {
+	dir := t.TempDir()
+	logDir := filepath.Join(dir, "chat-log")
+	if err := os.Mkdir(logDir, 0755); err != nil {
+		t.Fatalf("Error: Cannot create dir: %v", err)
+	}
+
+	writeLog := func(name string, messages ...ChatMessage) *ChatLog {
+		filePath := filepath.Join(logDir, name)
+		chatLog := &ChatLog{FilePath: &filePath, ChatLogToml: ChatLogToml{Messages: messages}}
+		if err := chatLog.FlushFile(); err != nil {
+			t.Fatalf("Error: Cannot write log: %v", err)
+		}
+
+		return chatLog
+	}
+
+	grpc := writeLog("grpc.toml",
+		ChatMessage{Role: "user", Content: "grpc deadline"},
+		ChatMessage{Role: "assistant", Content: "use a context deadline"},
+	)
+	writeLog("cooking.toml", ChatMessage{Role: "user", Content: "pasta recipe"})
+
+	embedder := &keywordEmbedder{keywords: []string{"deadline", "pasta", "context"}}
+	index := NewVectorIndex(filepath.Join(dir, VectorIndexDirName), embedder, "test")
+
+	files, err := ListFiles(logDir, ListOption{})
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if err := index.Update(context.Background(), files); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if embedder.embedded != 3 {
+		t.Errorf("Expected 3 embedded messages but got %d", embedder.embedded)
+	}
+
+	grpc.AddChatMessage(ChatMessage{Role: "user", Content: "thanks"})
+	if err := grpc.FlushFile(); err != nil {
+		t.Fatalf("Error: Cannot write log: %v", err)
+	}
+	if err := index.UpdateChatLog(context.Background(), grpc); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if embedder.embedded != 4 {
+		t.Errorf("Expected only the new message to be embedded but got %d in total", embedder.embedded)
+	}
+
+	results, err := index.Search(context.Background(), SemanticQuery{Text: "deadline"})
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if len(results) != 2 || filepath.Base(results[0].FilePath) != "grpc.toml" {
+		t.Fatalf("Expected grpc.toml to rank first but got %+v", results)
+	}
+	if results[0].Match.MessageIndex != 0 {
+		t.Errorf("Expected the first message to match best but got %d", results[0].Match.MessageIndex)
+	}
+
+	if err := os.Remove(filepath.Join(logDir, "cooking.toml")); err != nil {
+		t.Fatalf("Error: Cannot remove log: %v", err)
+	}
+	files, err = ListFiles(logDir, ListOption{})
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if err := index.Update(context.Background(), files); err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	results, err = index.Search(context.Background(), SemanticQuery{Text: "pasta"})
+	if err != nil {
+		t.Fatalf("Error: Return err func: %v", err)
+	}
+	if len(results) != 1 {
+		t.Errorf("Expected the removed log to be dropped but got %+v", results)
+	}
+}